@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"targeting-engine/internal/bulk"
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+	"targeting-engine/internal/service"
+)
+
+const exportUsage = `usage: targeting-engine export [flags] [file]
+
+Writes every campaign with its targeting rules to file, or to stdout, as JSON
+or CSV. The format defaults to the file's extension, and to JSON.
+`
+
+const importUsage = `usage: targeting-engine import [flags] file
+
+Makes the stored campaigns and rules match a JSON or CSV file, all at once or
+not at all. Imported campaigns get exactly the rules in the file; the others
+are kept unless --prune is given. The format defaults to the file's extension.
+`
+
+// runExport runs the export subcommand with the arguments that follow it.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("targeting-engine export", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), exportUsage, "\nflags:\n")
+		flags.PrintDefaults()
+	}
+	formatName := flags.String("format", "", "file format, json or csv")
+	settings, _, err := loadConfig(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errors.New("export: expected at most one file")
+	}
+	format, err := bulkFormat(*formatName, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, settings.Database.PostgresURI)
+	if err != nil {
+		return err
+	}
+	defer repo.Close(ctx)
+
+	data, err := service.NewCampaignService(repo).Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if flags.NArg() == 1 {
+		file, err := os.Create(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := bulk.Write(out, format, data); err != nil {
+		return err
+	}
+	if flags.NArg() == 1 {
+		fmt.Fprintf(os.Stderr, "Exported %d campaigns and %d rules to %s\n", len(data.Campaigns), len(data.Rules), flags.Arg(0))
+	}
+	return nil
+}
+
+// runImport runs the import subcommand with the arguments that follow it.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("targeting-engine import", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), importUsage, "\nflags:\n")
+		flags.PrintDefaults()
+	}
+	formatName := flags.String("format", "", "file format, json or csv")
+	var opts service.ImportOptions
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "show what would change without changing it")
+	flags.BoolVar(&opts.Prune, "prune", false, "delete the campaigns that aren't in the file")
+	settings, _, err := loadConfig(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("import: expected one file")
+	}
+	format, err := bulkFormat(*formatName, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := bulk.Read(file, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", flags.Arg(0), err)
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, settings.Database.PostgresURI)
+	if err != nil {
+		return err
+	}
+	defer repo.Close(ctx)
+
+	plan, err := service.NewCampaignService(repo).Import(ctx, data, opts)
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		return fmt.Errorf("%s: %w", flags.Arg(0), err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, change := range plan.Changes {
+		fmt.Println(change)
+	}
+	verb := "Imported"
+	if plan.DryRun {
+		verb = "Dry run, would import"
+	}
+	fmt.Printf("%s: %d created, %d updated, %d deleted, %d unchanged\n", verb,
+		plan.Summary.Created, plan.Summary.Updated, plan.Summary.Deleted, plan.Summary.Unchanged)
+	return nil
+}
+
+// bulkFormat picks the format named by a flag or, failing that, by the
+// file's extension.
+func bulkFormat(name, file string) (bulk.Format, error) {
+	if name == "" && strings.EqualFold(filepath.Ext(file), ".csv") {
+		name = string(bulk.FormatCSV)
+	}
+	return bulk.ParseFormat(name)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+	"targeting-engine/internal/service"
+)
+
+const explainUsage = `usage: targeting-engine explain [flags]
+
+Tells, for every campaign, whether a delivery request with the given params
+would return it and why, checking its status, schedule, each targeting rule
+against the request's value, expression, creatives, frequency caps, budgets
+and auction floor. Nothing is recorded or charged.
+`
+
+// runExplain runs the explain subcommand with the arguments that follow it.
+func runExplain(args []string) error {
+	flags := flag.NewFlagSet("targeting-engine explain", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), explainUsage, "\nflags:\n")
+		flags.PrintDefaults()
+	}
+	var req models.DeliveryRequest
+	flags.StringVar(&req.App, "app", "", "app of the request (required)")
+	flags.StringVar(&req.OS, "os", "", "OS of the request (required)")
+	flags.StringVar(&req.Country, "country", "", "country of the request (required)")
+	flags.StringVar(&req.OSVersion, "os-version", "", "OS version of the request")
+	flags.StringVar(&req.AppVersion, "app-version", "", "app version of the request")
+	flags.StringVar(&req.DeviceID, "device-id", "", "device ID, for frequency caps and experiments")
+	flags.StringVar(&req.Size, "size", "", "creative size, such as 300x250")
+	flags.StringVar(&req.Language, "lang", "", "creative language, such as en-US")
+	campaignID := flags.String("campaign", "", "only explain this campaign")
+	asJSON := flags.Bool("json", false, "print the explanation as JSON, as /v1/delivery/explain does")
+	settings, _, err := loadConfig(flags, args)
+	if err != nil {
+		return err
+	}
+	if req.App == "" || req.OS == "" || req.Country == "" {
+		flags.Usage()
+		return errors.New("explain: --app, --os and --country are required")
+	}
+
+	ctx := context.Background()
+	var opts []service.Option
+	var repo repository.Repository
+	switch settings.DBType {
+	case "memory":
+		return errors.New("explain: campaigns kept in memory can't be read from another process")
+	case "file":
+		if repo, err = repository.NewFileRepository(settings.CampaignsFile); err != nil {
+			return err
+		}
+	default:
+		postgresStore, err := repository.NewPostgresRepository(ctx, settings.Database.PostgresURI)
+		if err != nil {
+			return err
+		}
+		defer postgresStore.Close(ctx)
+		if settings.FrequencyStore == "postgres" {
+			opts = append(opts, service.WithFrequencyStore(postgresStore.FrequencyStore()))
+		}
+		if settings.BudgetStore == "postgres" {
+			opts = append(opts, service.WithBudgetStore(postgresStore.BudgetStore()))
+		}
+		repo = postgresStore
+	}
+	auctioneer, err := newAuction(settings.Auction)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, service.WithAuction(auctioneer))
+
+	matcher := service.NewTargetingService(repo, opts...)
+	if err := matcher.Refresh(ctx); err != nil {
+		return err
+	}
+	explanation, err := matcher.Explain(ctx, req)
+	if err != nil {
+		return err
+	}
+	if *campaignID != "" {
+		var found []models.CampaignExplanation
+		for _, c := range explanation.Campaigns {
+			if c.CID == *campaignID {
+				found = append(found, c)
+			}
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("explain: %w", repository.ErrCampaignNotFound)
+		}
+		explanation.Campaigns = found
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explanation)
+	}
+	return printExplanation(os.Stdout, explanation)
+}
+
+// printExplanation writes an explanation as a table of checks per campaign.
+func printExplanation(w io.Writer, explanation *models.Explanation) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range explanation.Campaigns {
+		verdict := "eligible"
+		if !c.Eligible {
+			verdict = "not delivered: " + c.Reason
+		}
+		if c.Experiment != "" {
+			verdict += fmt.Sprintf(" (experiment %s, arm %s)", c.Experiment, c.Arm)
+		}
+		// Without a tab, the line ends the table of the previous campaign.
+		fmt.Fprintf(tw, "%s: %s\n", c.CID, verdict)
+
+		for _, check := range c.Checks {
+			outcome := "pass"
+			if !check.Pass {
+				outcome = "FAIL"
+			}
+			name := check.Check
+			if check.Check == models.CheckRule {
+				name = strings.Join(strings.Fields(fmt.Sprintf("%s %s %s %s", check.Check, check.Dimension, check.RuleType, check.MatchType)), " ") +
+					" [" + strings.Join(check.Values, " ") + "]"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", outcome, name, check.Reason)
+		}
+	}
+	return tw.Flush()
+}
@@ -2,58 +2,263 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"strconv"
 	"syscall"
 	"time"
+	// Campaign time zones must resolve even in images without tzdata.
+	_ "time/tzdata"
 
 	"targeting-engine/configs"
+	"targeting-engine/internal/auction"
+	"targeting-engine/internal/budget"
+	"targeting-engine/internal/frequency"
 	"targeting-engine/internal/handlers"
+	"targeting-engine/internal/health"
+	"targeting-engine/internal/logging"
+	"targeting-engine/internal/metrics"
 	"targeting-engine/internal/middleware"
 	"targeting-engine/internal/repository"
 	"targeting-engine/internal/service"
+	"targeting-engine/internal/tracing"
 )
 
+// commands are the subcommands run instead of the server, e.g.
+// "targeting-engine migrate up".
+var commands = map[string]func(args []string) error{
+	"migrate": runMigrate,
+	"seed":    runSeed,
+	"export":  runExport,
+	"import":  runImport,
+	"explain": runExplain,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	// Catch SIGHUP before anything else, so one sent during startup is
+	// buffered and applied once reloading starts rather than killing us.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
 	// Get our settings
-	settings := configs.NewConfig()
-	settings.LoadFromEnv()
+	settings, printConfig, err := loadConfig(flag.NewFlagSet("targeting-engine", flag.ExitOnError), os.Args[1:])
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	if printConfig {
+		if err := settings.Print(os.Stdout); err != nil {
+			fatal("Can't print configuration", err)
+		}
+		return
+	}
+
+	// Set up logging; the level can change on reload.
+	logLevel := new(slog.LevelVar)
+	level, err := logging.ParseLevel(settings.LogLevel)
+	if err != nil {
+		fatal("Invalid log level", err)
+	}
+	logLevel.Set(level)
+	logger, err := logging.New(os.Stdout, settings.LogFormat, logLevel)
+	if err != nil {
+		fatal("Invalid log format", err)
+	}
+	slog.SetDefault(logger)
 
-	// Set up PostgreSQL connection
 	ctx := context.Background()
 
-	postgresStore, err := repository.NewPostgresRepository(ctx, settings.Database.PostgresURI)
+	shutdownTracing, err := tracing.Setup(ctx, settings.Tracing)
 	if err != nil {
-		log.Fatalf("Dang! Can't connect to PostgreSQL: %v", err)
+		fatal("Can't set up tracing", err)
 	}
 	defer func() {
-		log.Println("Closing PostgreSQL connection...")
-		if err := postgresStore.Close(ctx); err != nil {
-			log.Printf("Whoops! Error closing PostgreSQL: %v", err)
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Couldn't flush traces", "error", err)
 		}
 	}()
 
-	log.Println("Adding some test data to PostgreSQL...")
-	if err := postgresStore.InitTestData(ctx); err != nil {
-		log.Printf("Hmm, couldn't add test data: %v", err)
+	// Set up the campaign store
+
+	var store campaignStore
+	var campaignsFile *repository.FileRepository
+	var exposures frequency.Store = frequency.NewMemoryStore(settings.FrequencyMaxDevices)
+	var budgets budget.Store = budget.NewMemoryStore()
+
+	switch settings.DBType {
+	case "memory":
+		slog.Warn("Keeping campaigns in memory, they are lost on exit")
+		store = repository.NewMemoryRepository()
+	case "file":
+		campaignsFile, err = repository.NewFileRepository(settings.CampaignsFile)
+		if err != nil {
+			fatal("Can't load the campaigns file", err)
+		}
+		store = campaignsFile
+	default:
+		postgresStore, err := repository.NewPostgresRepository(ctx, settings.Database.PostgresURI)
+		if err != nil {
+			fatal("Can't connect to PostgreSQL", err)
+		}
+		defer func() {
+			slog.Info("Closing PostgreSQL connection")
+			if err := postgresStore.Close(ctx); err != nil {
+				slog.Error("Couldn't close PostgreSQL connection", "error", err)
+			}
+		}()
+
+		pending, err := postgresStore.PendingMigrations(ctx)
+		if err != nil {
+			fatal("Can't check the database schema", err)
+		}
+		if len(pending) > 0 {
+			fatal("The database schema is out of date", fmt.Errorf("%d pending migrations, run migrate up", len(pending)))
+		}
+
+		if settings.FrequencyStore == "postgres" {
+			exposures = postgresStore.FrequencyStore()
+		}
+		if settings.BudgetStore == "postgres" {
+			budgets = postgresStore.BudgetStore()
+		}
+		store = postgresStore
 	}
 
-	campaignMatcher := service.NewTargetingService(postgresStore)
-	campaignHandler := handlers.NewDeliveryHandler(campaignMatcher)
+	// Metrics stay nil, and record nothing, unless they are enabled.
+	var collector *metrics.Metrics
+	var repo repository.Repository = store
+	if settings.EnableMetrics {
+		collector = metrics.New()
+		repo = metrics.InstrumentRepository(store, collector)
+	}
+
+	ranker, err := service.NewRanker(settings.Ranking)
+	if err != nil {
+		fatal("Invalid ranking", err)
+	}
+	auctioneer, err := newAuction(settings.Auction)
+	if err != nil {
+		fatal("Invalid auction", err)
+	}
+	campaignMatcher := service.NewTargetingService(repo,
+		service.WithFrequencyStore(exposures),
+		service.WithBudgetStore(budgets),
+		service.WithRanker(ranker),
+		service.WithAuction(auctioneer),
+		service.WithMetrics(collector),
+	)
+	if err := campaignMatcher.Refresh(ctx); err != nil {
+		fatal("Can't load targeting data", err)
+	}
+
+	// Reloadable settings are read from live from here on.
+	live := configs.NewLive(settings, func() (*configs.Config, error) {
+		cfg, _, err := loadConfig(flag.NewFlagSet("targeting-engine", flag.ContinueOnError), os.Args[1:])
+		return cfg, err
+	})
+	live.OnChange(func(old, new *configs.Config) {
+		if new.LogLevel != old.LogLevel {
+			// Validated with the rest of the configuration.
+			level, _ := logging.ParseLevel(new.LogLevel)
+			logLevel.Set(level)
+		}
+		if new.CacheRefreshInterval != old.CacheRefreshInterval {
+			campaignMatcher.SetRefreshInterval(new.CacheRefreshInterval)
+		}
+		if !reflect.DeepEqual(new.Auction, old.Auction) {
+			// Validated with the rest of the configuration.
+			auctioneer, _ := newAuction(new.Auction)
+			campaignMatcher.SetAuction(auctioneer)
+		}
+	})
+
+	refreshCtx, stopRefresh := context.WithCancel(ctx)
+	defer stopRefresh()
+	go reloadOnSignal(hup, live)
+	if settings.ConfigWatchInterval > 0 {
+		go live.WatchFile(refreshCtx, settings.ConfigWatchInterval, logReload)
+	}
+	if campaignsFile != nil && settings.CampaignsWatchInterval > 0 {
+		go campaignsFile.WatchFile(refreshCtx, settings.CampaignsWatchInterval, func(err error) {
+			if err != nil {
+				slog.Error("Keeping the last good campaigns, the campaigns file is invalid", "file", settings.CampaignsFile, "error", err)
+				return
+			}
+			slog.Info("Campaigns file reloaded", "file", settings.CampaignsFile)
+		})
+	}
+	go func() {
+		err := campaignMatcher.Watch(refreshCtx, store, settings.CacheRefreshInterval)
+		if err != nil {
+			slog.Warn("Can't watch for targeting changes, polling instead", "error", err)
+			campaignMatcher.RefreshPeriodically(refreshCtx, settings.CacheRefreshInterval)
+		}
+	}()
+
+	campaignHandler := handlers.NewDeliveryHandler(campaignMatcher, handlers.WithMetrics(collector))
+	adminHandler := handlers.NewAdminHandler(service.NewCampaignService(repo))
 
 	router := http.NewServeMux()
 
 	router.Handle("/v1/delivery", campaignHandler)
-	if settings.EnableHealthCheck {
-		router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("OK"))
+	router.Handle("/v1/delivery/explain", handlers.NewExplainHandler(campaignMatcher))
+	router.Handle("/v1/click", handlers.NewClickHandler(campaignMatcher))
+	router.Handle("/v1/admin/", adminHandler)
+
+	probes := health.NewChecker(2 * time.Second)
+	probes.Add("database", func(ctx context.Context) (string, error) {
+		return "", repo.Ping(ctx)
+	})
+	probes.Add("targeting_data", func(ctx context.Context) (string, error) {
+		staleness, err := campaignMatcher.Staleness()
+		if err != nil {
+			return "", err
+		}
+		detail := "in sync " + staleness.Round(time.Millisecond).String() + " ago"
+		if max := live.Get().ReadinessMaxStaleness; staleness > max {
+			return detail, fmt.Errorf("stale for more than %s", max)
+		}
+		return detail, nil
+	})
+
+	healthChecks := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !live.Get().EnableHealthCheck {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
 		})
 	}
-	handler := middleware.LoggingMiddleware(router)
+	router.Handle("GET /livez", healthChecks(probes.LiveHandler()))
+	router.Handle("GET /readyz", healthChecks(probes.ReadyHandler()))
+	router.Handle("/health", healthChecks(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})))
+	var handler http.Handler = router
+	if settings.EnableMetrics {
+		handler = middleware.MetricsMiddleware(collector, router)
+	}
+	handler = middleware.LoggingMiddleware(logger, func() float64 {
+		return live.Get().DeliveryLogSampleRate
+	}, handler)
+	handler = middleware.TracingMiddleware(router, handler)
+	handler = middleware.RequestIDMiddleware(handler)
 
 	server := &http.Server{
 		Addr:    ":" + strconv.Itoa(settings.Port),
@@ -61,23 +266,111 @@ func main() {
 	}
 
 	go func() {
-		log.Printf("Starting server on port %d", settings.Port)
+		slog.Info("Starting server", "port", settings.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Uh oh! Server couldn't start: %v", err)
+			fatal("Server couldn't start", err)
 		}
 	}()
 
+	var metricsServer *http.Server
+	if settings.EnableMetrics {
+		metricsRouter := http.NewServeMux()
+		metricsRouter.Handle("/metrics", collector.Handler())
+		metricsServer = &http.Server{
+			Addr:    ":" + strconv.Itoa(settings.MetricsPort),
+			Handler: metricsRouter,
+		}
+
+		go func() {
+			slog.Info("Serving metrics", "port", settings.MetricsPort)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Metrics server couldn't start", err)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	// Fail readiness first so load balancers stop routing here, then drain.
+	probes.Drain()
+	if delay := live.Get().ShutdownDelay; delay > 0 {
+		slog.Info("Failing readiness before shutting down", "delay", delay)
+		time.Sleep(delay)
+	}
+	slog.Info("Shutting down server")
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Server had to force quit: %v", err)
+		fatal("Server had to force quit", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Metrics server had to force quit", "error", err)
+		}
+	}
+
+	slog.Info("Server shut down")
+}
+
+// campaignStore is a repository that pushes its changes, as the PostgreSQL,
+// memory and file repositories all do.
+type campaignStore interface {
+	repository.Repository
+	repository.Notifier
+}
+
+// newAuction returns the auction cfg configures, or nil for none.
+func newAuction(cfg configs.Auction) (*auction.Auction, error) {
+	if cfg.Type == "none" {
+		return nil, nil
+	}
+	floors := make([]auction.Floor, len(cfg.Floors))
+	for i, f := range cfg.Floors {
+		floors[i] = auction.Floor{App: f.App, Country: f.Country, CPM: f.CPM}
+	}
+	return auction.New(auction.Type(cfg.Type), cfg.ReservePrice, floors)
+}
+
+// loadConfig reads the configuration from args, the environment and the
+// config file. It is called again on every reload.
+func loadConfig(flags *flag.FlagSet, args []string) (*configs.Config, bool, error) {
+	printConfig := flags.Bool("print-config", false, "print the effective configuration and exit")
+	settings, err := configs.Load(flags, args)
+	return settings, *printConfig, err
+}
+
+// reloadOnSignal reloads the configuration on every SIGHUP received on hup.
+func reloadOnSignal(hup <-chan os.Signal, live *configs.Live) {
+	for range hup {
+		logReload(live.Reload())
 	}
+}
+
+func logReload(changes []configs.Change, err error) {
+	if err != nil {
+		slog.Error("Keeping the current configuration, reload failed", "error", err)
+		return
+	}
+
+	var applied, ignored []string
+	for _, change := range changes {
+		if change.Reloadable {
+			applied = append(applied, change.String())
+		} else {
+			ignored = append(ignored, change.String())
+		}
+	}
+	slog.Info("Configuration reloaded", "changed", applied)
+	if len(ignored) > 0 {
+		slog.Warn("Configuration changes need a restart", "changed", ignored)
+	}
+}
 
-	log.Println("Server shut down nicely")
+// fatal logs err and exits. Deferred calls are skipped, as with log.Fatal.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"targeting-engine/internal/repository"
+)
+
+const migrateUsage = `usage: targeting-engine migrate [flags] up|down [n]|status
+
+  up        apply every pending migration
+  down [n]  revert the last n applied migrations (default 1)
+  status    list migrations and when they were applied
+`
+
+// runMigrate runs the migrate subcommand with the arguments that follow it.
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("targeting-engine migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage, "\nflags:\n")
+		flags.PrintDefaults()
+	}
+	settings, _, err := loadConfig(flags, args)
+	if err != nil {
+		return err
+	}
+
+	action, rest := "", flags.Args()
+	if len(rest) > 0 {
+		action, rest = rest[0], rest[1:]
+	}
+	steps := 1
+	if action == "down" && len(rest) == 1 {
+		steps, err = strconv.Atoi(rest[0])
+		if err != nil || steps < 1 {
+			return fmt.Errorf("migrate down: %q is not a positive number of steps", rest[0])
+		}
+		rest = nil
+	}
+	if len(rest) > 0 || (action != "up" && action != "down" && action != "status") {
+		flags.Usage()
+		return errors.New("migrate: expected up, down [n] or status")
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, settings.Database.PostgresURI)
+	if err != nil {
+		return err
+	}
+	defer repo.Close(ctx)
+
+	switch action {
+	case "up":
+		applied, err := repo.MigrateUp(ctx)
+		printMigrations(os.Stdout, "Applied", applied, "The schema is up to date", err)
+		return err
+	case "down":
+		reverted, err := repo.MigrateDown(ctx, steps)
+		printMigrations(os.Stdout, "Reverted", reverted, "No migrations to revert", err)
+		return err
+	default:
+		status, err := repo.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	}
+}
+
+// printMigrations reports what an up or down run did, or none if it did nothing.
+func printMigrations(w io.Writer, verb string, migrations []repository.Migration, none string, err error) {
+	if len(migrations) == 0 && err == nil {
+		fmt.Fprintln(w, none)
+		return
+	}
+	for _, m := range migrations {
+		fmt.Fprintf(w, "%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"targeting-engine/internal/fixtures"
+	"targeting-engine/internal/repository"
+	"targeting-engine/internal/seed"
+)
+
+const seedUsage = `usage: targeting-engine seed [flags] [file]
+
+Upserts the campaigns, rules, expressions and creatives in a JSON or YAML
+fixtures file, or the built-in demo campaigns when no file is given, in one
+transaction. Seeding again with the same file changes nothing.
+`
+
+// runSeed runs the seed subcommand with the arguments that follow it.
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("targeting-engine seed", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), seedUsage, "\nflags:\n")
+		flags.PrintDefaults()
+	}
+	settings, _, err := loadConfig(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errors.New("seed: expected at most one fixtures file")
+	}
+
+	f, source := seed.Demo(), "demo campaigns"
+	if flags.NArg() == 1 {
+		source = flags.Arg(0)
+		if f, err = fixtures.Load(source); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, settings.Database.PostgresURI)
+	if err != nil {
+		return err
+	}
+	defer repo.Close(ctx)
+
+	if err := repo.ApplyBatch(ctx, repository.FixturesBatch(f)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Seeded %d campaigns, %d rules, %d expressions and %d creatives from %s\n",
+		len(f.Campaigns), len(f.Rules), len(f.Expressions), len(f.Creatives), source)
+	return nil
+}
@@ -0,0 +1,122 @@
+package configs
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Writing %s failed: %v", name, err)
+	}
+	return path
+}
+
+func load(args ...string) (*Config, error) {
+	return Load(flag.NewFlagSet("test", flag.ContinueOnError), args)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", "port: 8000\nmetrics_port: 9000\nlog_level: warn\ncache_refresh_interval: 1m\n")
+	t.Setenv("CONFIG_PATH", path)
+	t.Setenv("METRICS_PORT", "9100")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	cfg, err := load("--log-level", "error", "--enable-metrics")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Port != 8000 {
+		t.Errorf("Expected the file to set port but got %d", cfg.Port)
+	}
+	if cfg.CacheRefreshInterval != time.Minute {
+		t.Errorf("Expected a 1m refresh interval from the file but got %s", cfg.CacheRefreshInterval)
+	}
+	if cfg.MetricsPort != 9100 {
+		t.Errorf("Expected the environment to override the file but got %d", cfg.MetricsPort)
+	}
+	if cfg.LogLevel != "error" || !cfg.EnableMetrics {
+		t.Errorf("Expected flags to override everything but got %q, %v", cfg.LogLevel, cfg.EnableMetrics)
+	}
+	if cfg.FrequencyStore != "memory" {
+		t.Errorf("Expected unset values to keep their defaults but got %q", cfg.FrequencyStore)
+	}
+}
+
+func TestLoadJSONFile(t *testing.T) {
+	path := writeFile(t, "config.json", `{"port": 8081, "database": {"postgres_uri": "postgres://db/x"}}`)
+
+	cfg, err := load("--config", path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Port != 8081 || cfg.Database.PostgresURI != "postgres://db/x" {
+		t.Errorf("Expected the JSON file to be applied but got %+v", cfg)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "bad env", env: map[string]string{"PORT": "eighty"}, want: "invalid PORT"},
+		{name: "bad bool env", env: map[string]string{"ENABLE_METRICS": "yes please"}, want: "invalid ENABLE_METRICS"},
+		{name: "bad flag", args: []string{"--cache-refresh-interval", "soon"}, want: "invalid --cache-refresh-interval"},
+		{name: "unknown key", file: "prot: 80\n", want: "field prot not found"},
+		{name: "out of range", file: "port: 70000\nfrequency_store: redis\n", want: "invalid frequency_store"},
+		{name: "unknown db type", env: map[string]string{"DB_TYPE": "mongo"}, want: "invalid db_type"},
+		{name: "postgres store without postgres", env: map[string]string{"DB_TYPE": "memory", "BUDGET_STORE": "postgres"}, want: "invalid budget_store"},
+		{name: "floor without app or country", file: "auction:\n  floors:\n    - cpm: 1\n", want: "invalid auction.floors"},
+		{name: "unknown ranking", args: []string{"--ranking", "best"}, want: "invalid ranking"},
+		{name: "file store without file", env: map[string]string{"DB_TYPE": "file"}, want: "invalid campaigns_file"},
+		{name: "missing file", args: []string{"--config", "does-not-exist.json"}, want: "reading config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "--config", writeFile(t, "config.yaml", tt.file))
+			}
+
+			_, err := load(args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q but got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Database.PostgresURI = "postgres://admin:hunter2@db:5432/targeting"
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print failed: %v", err)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("Expected the password to be redacted, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "cache_refresh_interval: 30s") {
+		t.Errorf("Expected durations to be printed readably, got:\n%s", out.String())
+	}
+	if cfg.Database.PostgresURI != "postgres://admin:hunter2@db:5432/targeting" {
+		t.Error("Expected Print to leave the configuration untouched")
+	}
+}
@@ -0,0 +1,161 @@
+package configs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Change is one setting that differs between two configurations.
+type Change struct {
+	Key      string
+	Old, New string
+	// Reloadable is false for settings that only take effect on restart.
+	Reloadable bool
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// Live holds the current configuration. Readers call Get on every use, so a
+// reload takes effect without restarting anything.
+type Live struct {
+	current atomic.Pointer[Config]
+	load    func() (*Config, error)
+
+	// mu serializes reloads and guards listeners.
+	mu        sync.Mutex
+	listeners []func(old, new *Config)
+}
+
+// NewLive starts from cfg and reloads with load.
+func NewLive(cfg *Config, load func() (*Config, error)) *Live {
+	l := &Live{load: load}
+	l.current.Store(cfg)
+	return l
+}
+
+// Get returns the current configuration, which must not be modified.
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+// OnChange registers fn to be called after every reload that changed a
+// reloadable setting.
+func (l *Live) OnChange(fn func(old, new *Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
+// Reload loads the configuration again and swaps it in. Settings that need a
+// restart keep their current values; they are returned as changes that are
+// not Reloadable. An invalid configuration is rejected and the current one
+// stays in place.
+func (l *Live) Reload() ([]Change, error) {
+	next, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old := l.current.Load()
+	changes := Diff(old, next)
+
+	applied := *old
+	reloaded := false
+	for _, change := range changes {
+		if change.Reloadable {
+			reloaded = true
+		}
+	}
+	copyReloadable(&applied, next)
+
+	if reloaded {
+		l.current.Store(&applied)
+		for _, fn := range l.listeners {
+			fn(old, &applied)
+		}
+	}
+	return changes, nil
+}
+
+// WatchFile reloads the configuration whenever the config file changes,
+// checking every interval until ctx is done. report is called with the
+// outcome of each reload.
+func (l *Live) WatchFile(ctx context.Context, interval time.Duration, report func([]Change, error)) {
+	path := l.Get().File
+	if path == "" {
+		return
+	}
+
+	last, _ := os.Stat(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+				continue
+			}
+			last = info
+			report(l.Reload())
+		}
+	}
+}
+
+// Diff lists the settings that differ between old and new, keyed like the
+// config file. Secrets are redacted.
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	diffStruct(reflect.ValueOf(*old.Redacted()), reflect.ValueOf(*new.Redacted()), "", &changes)
+	return changes
+}
+
+func diffStruct(old, new reflect.Value, prefix string, changes *[]Change) {
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key == "-" || key == "" {
+			continue
+		}
+		key = prefix + key
+
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(old.Field(i), new.Field(i), key+".", changes)
+			continue
+		}
+
+		o, n := old.Field(i).Interface(), new.Field(i).Interface()
+		if !reflect.DeepEqual(o, n) {
+			*changes = append(*changes, Change{
+				Key:        key,
+				Old:        fmt.Sprint(o),
+				New:        fmt.Sprint(n),
+				Reloadable: field.Tag.Get("reload") == "true",
+			})
+		}
+	}
+}
+
+// copyReloadable copies the reloadable top-level settings from src to dst.
+func copyReloadable(dst, src *Config) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < d.NumField(); i++ {
+		if d.Type().Field(i).Tag.Get("reload") == "true" {
+			d.Field(i).Set(s.Field(i))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package configs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLiveReload(t *testing.T) {
+	next := NewConfig()
+	next.LogLevel = "debug"
+	next.Port = 9999
+	next.Database.PostgresURI = "postgres://admin:hunter2@db/x"
+	next.Auction.Floors = []Floor{{Country: "US", CPM: 1}}
+	var loadErr error
+
+	live := NewLive(NewConfig(), func() (*Config, error) {
+		cfg := *next
+		return &cfg, loadErr
+	})
+
+	var notified *Config
+	live.OnChange(func(old, new *Config) { notified = new })
+
+	changes, err := live.Reload()
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	cfg := live.Get()
+	if cfg.LogLevel != "debug" || len(cfg.Auction.Floors) != 1 {
+		t.Errorf("Expected log_level and auction floors to be reloaded but got %+v", cfg)
+	}
+	if cfg.Port != 8080 || cfg.Database.PostgresURI == next.Database.PostgresURI {
+		t.Errorf("Expected restart-only settings to be kept but got %+v", cfg)
+	}
+	if notified != cfg {
+		t.Error("Expected listeners to be notified with the new configuration")
+	}
+
+	reloadable := map[string]bool{}
+	for _, change := range changes {
+		reloadable[change.Key] = change.Reloadable
+		if change.Key == "database.postgres_uri" && change.New != "postgres://admin:xxxxx@db/x" {
+			t.Errorf("Expected secrets to be redacted in changes but got %q", change.New)
+		}
+	}
+	if len(changes) != 4 || !reloadable["log_level"] || !reloadable["auction.floors"] || reloadable["port"] || reloadable["database.postgres_uri"] {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+
+	loadErr = errors.New("invalid log_level")
+	next.LogLevel = "error"
+	if _, err := live.Reload(); err == nil {
+		t.Error("Expected a failed load to be reported")
+	}
+	if live.Get().LogLevel != "debug" {
+		t.Error("Expected a failed reload to keep the current configuration")
+	}
+}
+
+func TestLiveWatchFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", "log_level: info\n")
+	initial, err := load("--config", path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	live := NewLive(initial, func() (*Config, error) { return load("--config", path) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan []Change, 1)
+	go live.WatchFile(ctx, 10*time.Millisecond, func(changes []Change, err error) {
+		if err == nil {
+			reloaded <- changes
+		}
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("log_level: warn\ncache_refresh_interval: 5s\n"), 0o600); err != nil {
+		t.Fatalf("Writing config failed: %v", err)
+	}
+
+	select {
+	case changes := <-reloaded:
+		if len(changes) != 2 {
+			t.Errorf("Expected two changes but got %v", changes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the file change to trigger a reload")
+	}
+	if cfg := live.Get(); cfg.LogLevel != "warn" || cfg.CacheRefreshInterval != 5*time.Second {
+		t.Errorf("Expected the file's settings to be applied but got %+v", cfg)
+	}
+}
@@ -0,0 +1,110 @@
+// Package auction prices the campaigns competing for a delivery against
+// their bids and the floor prices of the app and country.
+package auction
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Type decides what auction winners pay.
+type Type string
+
+const (
+	// FirstPrice winners pay their own bid.
+	FirstPrice Type = "first_price"
+	// SecondPrice winners pay the next highest bid, or the floor when no
+	// bid is lower: a generalized second price auction when there are
+	// several winners.
+	SecondPrice Type = "second_price"
+)
+
+// Floor is the lowest CPM at which campaigns can win on an app, in a country
+// or on an app in a country. An empty App or Country matches any.
+type Floor struct {
+	App     string
+	Country string
+	CPM     float64
+}
+
+// Auction runs auctions of one type with fixed floor prices. It is
+// immutable and safe for concurrent use.
+type Auction struct {
+	typ Type
+	// reserve is the lowest price of any auction.
+	reserve float64
+	// floors maps a lowercased app and country, either possibly empty, to
+	// the floor there.
+	floors map[floorKey]float64
+}
+
+type floorKey struct {
+	app, country string
+}
+
+// New returns an auction of type typ. Campaigns never win below reserve,
+// whatever the floors.
+func New(typ Type, reserve float64, floors []Floor) (*Auction, error) {
+	if typ != FirstPrice && typ != SecondPrice {
+		return nil, fmt.Errorf("unknown auction type %q", typ)
+	}
+	a := &Auction{typ: typ, reserve: reserve, floors: make(map[floorKey]float64, len(floors))}
+	for _, f := range floors {
+		a.floors[floorKey{app: strings.ToLower(f.App), country: strings.ToLower(f.Country)}] = f.CPM
+	}
+	return a, nil
+}
+
+// Type returns the auction's type.
+func (a *Auction) Type() Type {
+	return a.typ
+}
+
+// Floor returns the lowest winning CPM on app in country: the floor set for
+// both, else for the app, else for the country, and never below the
+// reserve price.
+func (a *Auction) Floor(app, country string) float64 {
+	app, country = strings.ToLower(app), strings.ToLower(country)
+	for _, key := range []floorKey{{app, country}, {app, ""}, {"", country}} {
+		if floor, ok := a.floors[key]; ok {
+			if floor < a.reserve {
+				return a.reserve
+			}
+			return floor
+		}
+	}
+	return a.reserve
+}
+
+// Winner is a bid that cleared the floor and the price it pays.
+type Winner struct {
+	// Bid is the index of the winning bid.
+	Bid   int
+	Price float64
+}
+
+// Run auctions a delivery on app in country between bids, which are CPMs
+// listed in tie-breaking order: of two equal bids, the first wins. It returns
+// the bids at or above the floor, highest first, with their prices.
+func (a *Auction) Run(app, country string, bids []float64) []Winner {
+	floor := a.Floor(app, country)
+
+	var winners []Winner
+	for i, bid := range bids {
+		if bid >= floor {
+			winners = append(winners, Winner{Bid: i, Price: bid})
+		}
+	}
+	sort.SliceStable(winners, func(i, j int) bool { return bids[winners[i].Bid] > bids[winners[j].Bid] })
+
+	if a.typ == SecondPrice {
+		for i := range winners {
+			winners[i].Price = floor
+			if i+1 < len(winners) {
+				winners[i].Price = bids[winners[i+1].Bid]
+			}
+		}
+	}
+	return winners
+}
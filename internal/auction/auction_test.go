@@ -0,0 +1,74 @@
+package auction
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFloor(t *testing.T) {
+	a, err := New(SecondPrice, 0.5, []Floor{
+		{App: "com.king.candycrush", Country: "US", CPM: 4},
+		{App: "com.king.candycrush", CPM: 2},
+		{Country: "us", CPM: 1},
+		{Country: "DE", CPM: 0.1},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		app, country string
+		want         float64
+	}{
+		{app: "com.King.CandyCrush", country: "US", want: 4},
+		{app: "com.king.candycrush", country: "FR", want: 2},
+		{app: "com.other", country: "US", want: 1},
+		{app: "com.other", country: "DE", want: 0.5},
+		{app: "com.other", country: "FR", want: 0.5},
+	}
+	for _, tc := range tests {
+		if got := a.Floor(tc.app, tc.country); got != tc.want {
+			t.Errorf("Expected the floor on %s in %s to be %v but got %v", tc.app, tc.country, tc.want, got)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	floors := []Floor{{Country: "US", CPM: 1}}
+	bids := []float64{2, 0.5, 3, 2, 1}
+
+	tests := []struct {
+		typ     Type
+		country string
+		want    []Winner
+	}{
+		{
+			typ:     FirstPrice,
+			country: "US",
+			want:    []Winner{{Bid: 2, Price: 3}, {Bid: 0, Price: 2}, {Bid: 3, Price: 2}, {Bid: 4, Price: 1}},
+		},
+		{
+			typ:     SecondPrice,
+			country: "US",
+			want:    []Winner{{Bid: 2, Price: 2}, {Bid: 0, Price: 2}, {Bid: 3, Price: 1}, {Bid: 4, Price: 1}},
+		},
+		{
+			typ:     SecondPrice,
+			country: "FR",
+			want:    []Winner{{Bid: 2, Price: 2}, {Bid: 0, Price: 2}, {Bid: 3, Price: 1}, {Bid: 4, Price: 0.5}, {Bid: 1, Price: 0}},
+		},
+	}
+	for _, tc := range tests {
+		a, err := New(tc.typ, 0, floors)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if got := a.Run("app", tc.country, bids); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s in %s: expected %+v but got %+v", tc.typ, tc.country, tc.want, got)
+		}
+	}
+
+	if _, err := New("third_price", 0, nil); err == nil {
+		t.Error("Expected an unknown auction type to be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+// Package budget tracks how many impressions campaigns have delivered and
+// what they have spent, and paces delivery against their budgets.
+package budget
+
+import (
+	"context"
+	"sync"
+)
+
+// Usage is what a campaign has delivered on one day and over its lifetime.
+type Usage struct {
+	DailyImpressions    int64
+	DailySpend          float64
+	LifetimeImpressions int64
+	LifetimeSpend       float64
+}
+
+// Key identifies a campaign's usage on a day, written as "2006-01-02" in the
+// campaign's time zone.
+type Key struct {
+	CampaignID string
+	Day        string
+}
+
+// Store keeps campaign usage counters.
+type Store interface {
+	// Usage returns the usage for each key, by campaign ID. Campaigns
+	// without any delivery may be missing.
+	Usage(ctx context.Context, keys []Key) (map[string]Usage, error)
+	// Add counts delivered impressions and spend and returns the new usage.
+	// Negative amounts give back what an earlier Reserve took.
+	Add(ctx context.Context, key Key, impressions int64, spend float64) (Usage, error)
+	// Reserve adds impressions and spend like Add, but only if allow approves
+	// the usage before the add. The check and the add are one atomic step,
+	// so concurrent requests can't overspend. It returns the new usage and
+	// whether the add happened.
+	Reserve(ctx context.Context, key Key, impressions int64, spend float64, allow func(Usage) bool) (Usage, bool, error)
+}
+
+// MemoryStore keeps usage in process memory. Counters start from zero on
+// every restart, so it suits tests and single instances without lifetime budgets.
+type MemoryStore struct {
+	mu        sync.Mutex
+	campaigns map[string]*counters
+}
+
+type counters struct {
+	Usage
+	day string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{campaigns: make(map[string]*counters)}
+}
+
+func (s *MemoryStore) Usage(ctx context.Context, keys []Key) (map[string]Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := make(map[string]Usage, len(keys))
+	for _, key := range keys {
+		if c, ok := s.campaigns[key.CampaignID]; ok {
+			usage[key.CampaignID] = c.on(key.Day)
+		}
+	}
+	return usage, nil
+}
+
+func (s *MemoryStore) Add(ctx context.Context, key Key, impressions int64, spend float64) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(key, impressions, spend), nil
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key Key, impressions int64, spend float64, allow func(Usage) bool) (Usage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var usage Usage
+	if c, ok := s.campaigns[key.CampaignID]; ok {
+		usage = c.on(key.Day)
+	}
+	if !allow(usage) {
+		return usage, false, nil
+	}
+	return s.add(key, impressions, spend), true, nil
+}
+
+// add counts impressions and spend. s.mu must be held.
+func (s *MemoryStore) add(key Key, impressions int64, spend float64) Usage {
+	c, ok := s.campaigns[key.CampaignID]
+	if !ok {
+		c = &counters{}
+		s.campaigns[key.CampaignID] = c
+	}
+	c.Usage = c.on(key.Day)
+	c.day = key.Day
+
+	c.DailyImpressions += impressions
+	c.DailySpend += spend
+	c.LifetimeImpressions += impressions
+	c.LifetimeSpend += spend
+	return c.Usage
+}
+
+// on returns the usage as seen on day, with the daily counters reset when
+// they belong to another day.
+func (c *counters) on(day string) Usage {
+	usage := c.Usage
+	if c.day != day {
+		usage.DailyImpressions = 0
+		usage.DailySpend = 0
+	}
+	return usage
+}
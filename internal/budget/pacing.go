@@ -0,0 +1,60 @@
+package budget
+
+import (
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+// evenPacingSlack lets an evenly paced campaign run this share of its daily
+// budget ahead of schedule, so it isn't starved right after midnight.
+const evenPacingSlack = 0.02
+
+// Exhausted reports whether the campaign has used up its lifetime budget.
+func Exhausted(c *models.Campaign, usage Usage) bool {
+	return (c.LifetimeImpressionBudget > 0 && usage.LifetimeImpressions >= c.LifetimeImpressionBudget) ||
+		(c.LifetimeSpendBudget > 0 && usage.LifetimeSpend >= c.LifetimeSpendBudget)
+}
+
+// Eligible reports whether the campaign may deliver another impression at
+// now, given its usage and the time of day in its time zone.
+//
+// ASAP campaigns deliver until a budget runs out. EVEN campaigns spread their
+// daily budgets over the day: they are held back whenever they have used more
+// of the day's budget than the share of the day that has passed.
+func Eligible(c *models.Campaign, usage Usage, now time.Time, location *time.Location) bool {
+	if Exhausted(c, usage) {
+		return false
+	}
+	if c.DailyImpressionBudget > 0 && usage.DailyImpressions >= c.DailyImpressionBudget {
+		return false
+	}
+	if c.DailySpendBudget > 0 && usage.DailySpend >= c.DailySpendBudget {
+		return false
+	}
+	if c.Pacing != models.PacingEven {
+		return true
+	}
+
+	allowed := dayElapsed(now, location) + evenPacingSlack
+	if c.DailyImpressionBudget > 0 && float64(usage.DailyImpressions) >= allowed*float64(c.DailyImpressionBudget) {
+		return false
+	}
+	if c.DailySpendBudget > 0 && usage.DailySpend >= allowed*c.DailySpendBudget {
+		return false
+	}
+	return true
+}
+
+// Day returns the day now falls on in location, as used in Keys.
+func Day(now time.Time, location *time.Location) string {
+	return now.In(location).Format("2006-01-02")
+}
+
+// dayElapsed returns the share of the local day that has passed at now.
+func dayElapsed(now time.Time, location *time.Location) float64 {
+	local := now.In(location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	next := midnight.AddDate(0, 0, 1)
+	return float64(local.Sub(midnight)) / float64(next.Sub(midnight))
+}
@@ -0,0 +1,79 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+func TestEligible(t *testing.T) {
+	noon := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		campaign models.Campaign
+		usage    Usage
+		now      time.Time
+		want     bool
+	}{
+		{"no budget", models.Campaign{}, Usage{DailyImpressions: 1e6}, noon, true},
+		{"asap under daily", models.Campaign{DailyImpressionBudget: 100}, Usage{DailyImpressions: 99}, noon, true},
+		{"asap daily spent", models.Campaign{DailyImpressionBudget: 100}, Usage{DailyImpressions: 100}, noon, false},
+		{"daily spend spent", models.Campaign{DailySpendBudget: 5}, Usage{DailySpend: 5}, noon, false},
+		{"lifetime impressions", models.Campaign{LifetimeImpressionBudget: 10}, Usage{LifetimeImpressions: 10}, noon, false},
+		{"lifetime spend", models.Campaign{LifetimeSpendBudget: 10}, Usage{LifetimeSpend: 10.5}, noon, false},
+		{"even behind schedule", models.Campaign{DailyImpressionBudget: 100, Pacing: models.PacingEven}, Usage{DailyImpressions: 40}, noon, true},
+		{"even ahead of schedule", models.Campaign{DailyImpressionBudget: 100, Pacing: models.PacingEven}, Usage{DailyImpressions: 60}, noon, false},
+		{"even slack after midnight", models.Campaign{DailyImpressionBudget: 100, Pacing: models.PacingEven}, Usage{DailyImpressions: 1}, noon.Add(-12 * time.Hour), true},
+		{"even spend", models.Campaign{DailySpendBudget: 10, Pacing: models.PacingEven}, Usage{DailySpend: 6}, noon, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Eligible(&tt.campaign, tt.usage, tt.now, time.UTC); got != tt.want {
+				t.Errorf("Expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEligibleUsesCampaignTimeZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+	campaign := models.Campaign{DailyImpressionBudget: 100, Pacing: models.PacingEven}
+
+	// 03:00 UTC is noon in Tokyo, so half the day's budget is available.
+	now := time.Date(2024, 6, 1, 3, 0, 0, 0, time.UTC)
+	if !Eligible(&campaign, Usage{DailyImpressions: 45}, now, tokyo) {
+		t.Error("Expected the campaign to be paced by Tokyo time")
+	}
+	if Day(now, tokyo) != "2024-06-01" || Day(now.Add(-13*time.Hour), tokyo) != "2024-05-31" {
+		t.Error("Expected days to follow the campaign time zone")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	store.Add(ctx, Key{CampaignID: "a", Day: "2024-06-01"}, 2, 0.5)
+	usage, err := store.Add(ctx, Key{CampaignID: "a", Day: "2024-06-01"}, 1, 0.25)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if usage != (Usage{DailyImpressions: 3, DailySpend: 0.75, LifetimeImpressions: 3, LifetimeSpend: 0.75}) {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+
+	all, _ := store.Usage(ctx, []Key{{CampaignID: "a", Day: "2024-06-02"}, {CampaignID: "b", Day: "2024-06-02"}})
+	if all["a"] != (Usage{LifetimeImpressions: 3, LifetimeSpend: 0.75}) {
+		t.Errorf("Expected daily usage to reset on a new day but got %+v", all["a"])
+	}
+	if _, ok := all["b"]; ok {
+		t.Error("Expected no usage for a campaign without deliveries")
+	}
+}
@@ -0,0 +1,146 @@
+// Package bulk reads and writes campaigns with their targeting rules as JSON
+// or CSV, and works out what importing them changes.
+package bulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"targeting-engine/internal/models"
+)
+
+// Format is a bulk file format.
+type Format string
+
+const (
+	// FormatJSON is an object with "campaigns" and "rules" arrays, shaped
+	// like the admin API's resources and the seed fixtures.
+	FormatJSON Format = "json"
+	// FormatCSV has one row per campaign, with its rules in columns.
+	FormatCSV Format = "csv"
+)
+
+// ParseFormat reads a format name, ignoring case. Empty means JSON.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, use json or csv", name)
+	}
+}
+
+// Data is a set of campaigns and their targeting rules.
+type Data struct {
+	Campaigns []models.Campaign      `json:"campaigns"`
+	Rules     []models.TargetingRule `json:"rules"`
+
+	// The CSV lines the campaigns and rules were read from, so validation
+	// errors can name them.
+	campaignLines, ruleLines []int
+}
+
+// Sort orders campaigns by ID and rules by campaign and dimension, so
+// exports of the same data are identical. It forgets the CSV lines.
+func (d *Data) Sort() {
+	d.campaignLines, d.ruleLines = nil, nil
+	sort.Slice(d.Campaigns, func(i, j int) bool { return d.Campaigns[i].ID < d.Campaigns[j].ID })
+	sort.Slice(d.Rules, func(i, j int) bool {
+		a, b := d.Rules[i], d.Rules[j]
+		if a.CampaignID != b.CampaignID {
+			return a.CampaignID < b.CampaignID
+		}
+		return a.DimensionType < b.DimensionType
+	})
+}
+
+// Validate checks every campaign and rule, that campaign IDs are unique, and
+// that every rule belongs to one of the campaigns, at most one per dimension.
+func (d *Data) Validate() error {
+	campaigns := make(map[string]bool, len(d.Campaigns))
+	for i := range d.Campaigns {
+		c := &d.Campaigns[i]
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", d.campaignAt(i), err)
+		}
+		if campaigns[c.ID] {
+			return &models.ValidationError{Field: fmt.Sprintf("campaigns[%d].id", i), Message: "campaign " + c.ID + " is listed twice"}
+		}
+		campaigns[c.ID] = true
+	}
+
+	rules := make(map[string]bool, len(d.Rules))
+	for i := range d.Rules {
+		r := &d.Rules[i]
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", d.ruleAt(i), err)
+		}
+		if !campaigns[r.CampaignID] {
+			return &models.ValidationError{Field: fmt.Sprintf("rules[%d].campaign_id", i), Message: "campaign " + r.CampaignID + " is not in the import"}
+		}
+		key := r.CampaignID + "/" + string(r.DimensionType)
+		if rules[key] {
+			return &models.ValidationError{Field: fmt.Sprintf("rules[%d].dimension_type", i), Message: fmt.Sprintf("campaign %s has two %s rules", r.CampaignID, r.DimensionType)}
+		}
+		rules[key] = true
+	}
+	return nil
+}
+
+func (d *Data) campaignAt(i int) string {
+	if d.campaignLines != nil {
+		return fmt.Sprintf("line %d", d.campaignLines[i])
+	}
+	return fmt.Sprintf("campaigns[%d]", i)
+}
+
+func (d *Data) ruleAt(i int) string {
+	if d.ruleLines != nil {
+		return fmt.Sprintf("line %d: %s rule", d.ruleLines[i], d.Rules[i].DimensionType)
+	}
+	return fmt.Sprintf("rules[%d]", i)
+}
+
+// Read decodes data in format f. It is left to Validate, which importing
+// runs, to check the campaigns and rules.
+func Read(r io.Reader, f Format) (*Data, error) {
+	if f == FormatCSV {
+		return readCSV(r)
+	}
+	return readJSON(r)
+}
+
+// Write encodes d in format f.
+func Write(w io.Writer, f Format, d *Data) error {
+	if f == FormatCSV {
+		return writeCSV(w, d)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+func readJSON(r io.Reader) (*Data, error) {
+	var d Data
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&d); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for i := range d.Rules {
+		normalizeRule(&d.Rules[i])
+	}
+	return &d, nil
+}
+
+// normalizeRule uppercases the rule's enums, as the admin API does.
+func normalizeRule(r *models.TargetingRule) {
+	r.DimensionType = models.DimensionType(strings.ToUpper(string(r.DimensionType)))
+	r.RuleType = models.RuleType(strings.ToUpper(string(r.RuleType)))
+	r.MatchType = models.MatchType(strings.ToUpper(string(r.MatchType)))
+}
@@ -0,0 +1,166 @@
+package bulk
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+func testData() *Data {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	return &Data{
+		Campaigns: []models.Campaign{
+			{
+				ID: "spotify", Name: "Spotify, for everyone", ImageURL: "https://img", CTA: "Download", Status: models.StatusActive,
+				StartAt: &start, TimeZone: "Europe/Berlin",
+				Dayparts: models.Dayparts{{Days: []string{"MON"}, Start: "09:00", End: "17:00"}},
+				BidCPM:   2.5, DailyImpressionBudget: 1000, Pacing: models.PacingEven,
+			},
+			{ID: "duolingo", Name: "Duolingo", ImageURL: "https://img2", CTA: "Install", Status: models.StatusInactive},
+		},
+		Rules: []models.TargetingRule{
+			{CampaignID: "spotify", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"US", "CA"}},
+			{CampaignID: "spotify", DimensionType: models.DimensionApp, RuleType: models.Exclude, MatchType: models.MatchPrefix, Values: []string{"com.spam."}},
+			{CampaignID: "duolingo", DimensionType: models.DimensionOSVersion, RuleType: models.Include, Values: []string{">=10"}},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			want := testData()
+			want.Sort()
+
+			var buf bytes.Buffer
+			if err := Write(&buf, format, want); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			got, err := Read(&buf, format)
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			got.Sort()
+
+			plan, err := Diff(want, got, true)
+			if err != nil {
+				t.Fatalf("Diff failed: %v", err)
+			}
+			if len(plan.Changes) != 0 {
+				t.Errorf("Expected a lossless round trip but got changes %v", plan.Changes)
+			}
+		})
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	header := "id,name,image_url,cta,status,bid_cpm,os_rule,os_values\n"
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+	}{
+		{name: "Unknown column", csv: "id,colour\na,red\n", wantErr: `unknown column "colour"`},
+		{name: "No id column", csv: "name\nA\n", wantErr: "missing id column"},
+		{name: "Invalid campaign", csv: header + "a,A,https://a,Go,PAUSED,,,\n", wantErr: "line 2: invalid status"},
+		{name: "Bad number", csv: header + "a,A,https://a,Go,ACTIVE,cheap,,\n", wantErr: "line 2: column bid_cpm"},
+		{name: "Values without rule", csv: header + "a,A,https://a,Go,ACTIVE,,,iOS\n", wantErr: "line 2: column os_rule"},
+		{name: "Invalid rule", csv: header + "a,A,https://a,Go,ACTIVE,,MAYBE,iOS\n", wantErr: "line 2: OS rule: invalid rule_type"},
+		{
+			name:    "Duplicate campaign",
+			csv:     header + "a,A,https://a,Go,ACTIVE,,,\na,B,https://b,Go,ACTIVE,,,\n",
+			wantErr: "line 3: campaign a is already on line 2",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := Read(strings.NewReader(tc.csv), FormatCSV)
+			if err == nil {
+				err = data.Validate()
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected an error containing %q but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReadCSVRules(t *testing.T) {
+	data, err := Read(strings.NewReader("id,name,image_url,cta,status,os_rule,os_values\na,A,https://a,Go,ACTIVE,include, Android | iOS\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	want := []models.TargetingRule{{CampaignID: "a", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"Android", "iOS"}}}
+	if !reflect.DeepEqual(data.Rules, want) {
+		t.Errorf("Expected rules %+v but got %+v", want, data.Rules)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := testData()
+	incoming := testData()
+	incoming.Campaigns[0].Name = "Spotify"
+	incoming.Campaigns[0].BidCPM = 3
+	incoming.Campaigns = incoming.Campaigns[:1]
+	incoming.Campaigns = append(incoming.Campaigns, models.Campaign{ID: "netflix", Name: "Netflix", ImageURL: "https://n", CTA: "Watch", Status: models.StatusActive})
+	incoming.Rules = []models.TargetingRule{
+		{CampaignID: "spotify", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"US"}},
+	}
+
+	tests := []struct {
+		name    string
+		prune   bool
+		want    []string
+		summary Summary
+	}{
+		{
+			name: "Merge",
+			want: []string{
+				"update campaign spotify [name bid_cpm]",
+				"create campaign netflix",
+				"update COUNTRY rule of campaign spotify",
+				"delete APP rule of campaign spotify",
+			},
+			summary: Summary{Created: 1, Updated: 2, Deleted: 1},
+		},
+		{
+			name:  "Prune",
+			prune: true,
+			want: []string{
+				"update campaign spotify [name bid_cpm]",
+				"create campaign netflix",
+				"update COUNTRY rule of campaign spotify",
+				"delete APP rule of campaign spotify",
+				"delete campaign duolingo",
+			},
+			summary: Summary{Created: 1, Updated: 2, Deleted: 2},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := Diff(current, incoming, tc.prune)
+			if err != nil {
+				t.Fatalf("Diff failed: %v", err)
+			}
+			var got []string
+			for _, change := range plan.Changes {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected changes %q but got %q", tc.want, got)
+			}
+			if plan.Summary != tc.summary {
+				t.Errorf("Expected summary %+v but got %+v", tc.summary, plan.Summary)
+			}
+
+			batch := plan.Batch()
+			if len(batch.SaveCampaigns) != 2 || len(batch.SaveRules) != 1 || len(batch.DeleteRules) != 1 {
+				t.Errorf("Expected the batch to save 2 campaigns and 1 rule and delete 1 rule but got %+v", batch)
+			}
+		})
+	}
+}
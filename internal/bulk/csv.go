@@ -0,0 +1,290 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+// valueSeparator separates a rule's values within a CSV cell.
+const valueSeparator = "|"
+
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	numberColumn
+	timeColumn
+	// jsonColumn cells hold the field's JSON, e.g. dayparts.
+	jsonColumn
+)
+
+type column struct {
+	name string
+	kind columnKind
+}
+
+// campaignColumns are the CSV columns holding campaign fields, named after
+// the fields' JSON names.
+var campaignColumns = []column{
+	{"id", textColumn},
+	{"name", textColumn},
+	{"image_url", textColumn},
+	{"cta", textColumn},
+	{"status", textColumn},
+	{"priority", numberColumn},
+	{"weight", numberColumn},
+	{"creative_rotation", textColumn},
+	{"start_at", timeColumn},
+	{"end_at", timeColumn},
+	{"time_zone", textColumn},
+	{"dayparts", jsonColumn},
+	{"frequency_caps", jsonColumn},
+	{"bid_cpm", numberColumn},
+	{"daily_impression_budget", numberColumn},
+	{"lifetime_impression_budget", numberColumn},
+	{"daily_spend_budget", numberColumn},
+	{"lifetime_spend_budget", numberColumn},
+	{"pacing", textColumn},
+	{"experiment", jsonColumn},
+}
+
+// ruleColumns returns the columns holding a campaign's rule for dimension,
+// such as os_version_rule, os_version_match and os_version_values.
+func ruleColumns(dimension models.DimensionType) (rule, match, values string) {
+	prefix := strings.ToLower(string(dimension))
+	return prefix + "_rule", prefix + "_match", prefix + "_values"
+}
+
+func csvHeader() []string {
+	header := make([]string, 0, len(campaignColumns)+3*len(models.Dimensions))
+	for _, col := range campaignColumns {
+		header = append(header, col.name)
+	}
+	for _, dimension := range models.Dimensions {
+		rule, match, values := ruleColumns(dimension)
+		header = append(header, rule, match, values)
+	}
+	return header
+}
+
+// campaignCells formats a campaign's fields as CSV cells, keyed by column.
+// Empty fields are empty cells.
+func campaignCells(c models.Campaign) (map[string]string, error) {
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+
+	cells := make(map[string]string, len(campaignColumns))
+	for _, col := range campaignColumns {
+		raw, ok := fields[col.name]
+		if !ok {
+			continue
+		}
+		switch col.kind {
+		case textColumn:
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, err
+			}
+			cells[col.name] = s
+		case timeColumn:
+			var t time.Time
+			if err := json.Unmarshal(raw, &t); err != nil {
+				return nil, err
+			}
+			cells[col.name] = t.UTC().Format(time.RFC3339)
+		default:
+			cells[col.name] = string(raw)
+		}
+	}
+	return cells, nil
+}
+
+// parseCampaign reads a campaign from CSV cells keyed by column.
+func parseCampaign(cells map[string]string) (models.Campaign, error) {
+	var c models.Campaign
+	for _, col := range campaignColumns {
+		cell := strings.TrimSpace(cells[col.name])
+		if cell == "" {
+			continue
+		}
+
+		var raw []byte
+		switch col.kind {
+		case numberColumn:
+			if _, err := strconv.ParseFloat(cell, 64); err != nil {
+				return c, fmt.Errorf("column %s: %q is not a number", col.name, cell)
+			}
+			raw = []byte(cell)
+		case jsonColumn:
+			raw = []byte(cell)
+		default:
+			raw, _ = json.Marshal(cell)
+		}
+
+		// Decoding one field at a time keeps errors tied to their column.
+		field, _ := json.Marshal(col.name)
+		object := append(append(append([]byte("{"), field...), ':'), raw...)
+		object = append(object, '}')
+		if err := json.Unmarshal(object, &c); err != nil {
+			return c, fmt.Errorf("column %s: %w", col.name, err)
+		}
+	}
+	return c, nil
+}
+
+func readCSV(r io.Reader) (*Data, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("invalid CSV: missing header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	known := make(map[string]bool)
+	for _, name := range csvHeader() {
+		known[name] = true
+	}
+	seen := make(map[string]bool, len(header))
+	for _, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, fmt.Errorf("invalid CSV: unknown column %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("invalid CSV: column %q appears twice", name)
+		}
+		seen[name] = true
+	}
+	if !seen["id"] {
+		return nil, errors.New("invalid CSV: missing id column")
+	}
+
+	d := &Data{Campaigns: []models.Campaign{}, Rules: []models.TargetingRule{}, campaignLines: []int{}, ruleLines: []int{}}
+	lines := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		cells := make(map[string]string, len(header))
+		for i, name := range header {
+			cells[strings.ToLower(strings.TrimSpace(name))] = record[i]
+		}
+
+		campaign, err := parseCampaign(cells)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if first, ok := lines[campaign.ID]; ok {
+			return nil, fmt.Errorf("line %d: campaign %s is already on line %d", line, campaign.ID, first)
+		}
+		lines[campaign.ID] = line
+
+		rules, err := parseRules(campaign.ID, cells)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		d.Campaigns = append(d.Campaigns, campaign)
+		d.campaignLines = append(d.campaignLines, line)
+		for _, rule := range rules {
+			d.Rules = append(d.Rules, rule)
+			d.ruleLines = append(d.ruleLines, line)
+		}
+	}
+	return d, nil
+}
+
+// parseRules reads a campaign's rules from CSV cells keyed by column.
+func parseRules(campaignID string, cells map[string]string) ([]models.TargetingRule, error) {
+	var rules []models.TargetingRule
+	for _, dimension := range models.Dimensions {
+		ruleCol, matchCol, valuesCol := ruleColumns(dimension)
+		ruleType := strings.TrimSpace(cells[ruleCol])
+		match := strings.TrimSpace(cells[matchCol])
+		values := strings.TrimSpace(cells[valuesCol])
+		if ruleType == "" {
+			if match != "" || values != "" {
+				return nil, fmt.Errorf("column %s: must be set when %s or %s is", ruleCol, matchCol, valuesCol)
+			}
+			continue
+		}
+
+		rule := models.TargetingRule{
+			CampaignID:    campaignID,
+			DimensionType: dimension,
+			RuleType:      models.RuleType(ruleType),
+			MatchType:     models.MatchType(match),
+		}
+		for _, value := range strings.Split(values, valueSeparator) {
+			rule.Values = append(rule.Values, strings.TrimSpace(value))
+		}
+		normalizeRule(&rule)
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func writeCSV(w io.Writer, d *Data) error {
+	rulesByCampaign := make(map[string][]models.TargetingRule)
+	for _, rule := range d.Rules {
+		rulesByCampaign[rule.CampaignID] = append(rulesByCampaign[rule.CampaignID], rule)
+	}
+
+	header := csvHeader()
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, campaign := range d.Campaigns {
+		cells, err := campaignCells(campaign)
+		if err != nil {
+			return err
+		}
+		for _, rule := range rulesByCampaign[campaign.ID] {
+			ruleCol, matchCol, valuesCol := ruleColumns(rule.DimensionType)
+			for _, value := range rule.Values {
+				if strings.Contains(value, valueSeparator) {
+					return fmt.Errorf("campaign %s: %s value %q contains %q, which CSV can't hold; export as JSON", campaign.ID, rule.DimensionType, value, valueSeparator)
+				}
+			}
+			cells[ruleCol] = string(rule.RuleType)
+			if rule.MatchType.OrDefault() != models.MatchExact {
+				cells[matchCol] = string(rule.MatchType)
+			}
+			cells[valuesCol] = strings.Join(rule.Values, valueSeparator)
+		}
+
+		record := make([]string, len(header))
+		for i, name := range header {
+			record[i] = cells[name]
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
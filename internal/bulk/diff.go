@@ -0,0 +1,181 @@
+package bulk
+
+import (
+	"fmt"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+// Action is what an import does to one campaign or rule.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is one campaign or, when Dimension is set, one rule an import
+// creates, updates or deletes.
+type Change struct {
+	Action     Action               `json:"action"`
+	CampaignID string               `json:"campaign_id"`
+	Dimension  models.DimensionType `json:"dimension,omitempty"`
+	// Fields lists the campaign fields an update changes.
+	Fields []string `json:"fields,omitempty"`
+}
+
+func (c Change) String() string {
+	target := "campaign " + c.CampaignID
+	if c.Dimension != "" {
+		target = fmt.Sprintf("%s rule of campaign %s", c.Dimension, c.CampaignID)
+	}
+	if len(c.Fields) > 0 {
+		return fmt.Sprintf("%s %s %v", c.Action, target, c.Fields)
+	}
+	return fmt.Sprintf("%s %s", c.Action, target)
+}
+
+// Summary counts an import's changes by action. Unchanged counts the
+// imported campaigns and rules that are already stored as they are.
+type Summary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Plan is what importing a set of campaigns changes.
+type Plan struct {
+	DryRun  bool     `json:"dry_run"`
+	Summary Summary  `json:"summary"`
+	Changes []Change `json:"changes"`
+
+	batch repository.Batch
+}
+
+// Batch returns the repository writes that carry out the plan.
+func (p *Plan) Batch() repository.Batch {
+	return p.batch
+}
+
+func (p *Plan) add(change Change) {
+	p.Changes = append(p.Changes, change)
+	switch change.Action {
+	case ActionCreate:
+		p.Summary.Created++
+	case ActionUpdate:
+		p.Summary.Updated++
+	case ActionDelete:
+		p.Summary.Deleted++
+	}
+}
+
+// Diff plans the import of incoming over current. Imported campaigns get
+// exactly the rules imported with them. Campaigns missing from incoming are
+// kept unless prune is set, in which case they are deleted. Unchanged
+// campaigns and rules are counted but not written.
+func Diff(current, incoming *Data, prune bool) (*Plan, error) {
+	plan := &Plan{Changes: []Change{}}
+
+	existing := make(map[string]models.Campaign, len(current.Campaigns))
+	for _, c := range current.Campaigns {
+		existing[c.ID] = c
+	}
+	existingRules := make(map[repository.RuleKey]models.TargetingRule, len(current.Rules))
+	for _, r := range current.Rules {
+		existingRules[repository.RuleKey{CampaignID: r.CampaignID, Dimension: r.DimensionType}] = r
+	}
+
+	imported := make(map[string]bool, len(incoming.Campaigns))
+	for _, c := range incoming.Campaigns {
+		imported[c.ID] = true
+		old, ok := existing[c.ID]
+		if !ok {
+			plan.add(Change{Action: ActionCreate, CampaignID: c.ID})
+			plan.batch.SaveCampaigns = append(plan.batch.SaveCampaigns, c)
+			continue
+		}
+
+		fields, err := changedFields(old, c)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			plan.Summary.Unchanged++
+			continue
+		}
+		plan.add(Change{Action: ActionUpdate, CampaignID: c.ID, Fields: fields})
+		plan.batch.SaveCampaigns = append(plan.batch.SaveCampaigns, c)
+	}
+
+	importedRules := make(map[repository.RuleKey]bool, len(incoming.Rules))
+	for _, r := range incoming.Rules {
+		key := repository.RuleKey{CampaignID: r.CampaignID, Dimension: r.DimensionType}
+		importedRules[key] = true
+		old, ok := existingRules[key]
+		switch {
+		case !ok:
+			plan.add(Change{Action: ActionCreate, CampaignID: r.CampaignID, Dimension: r.DimensionType})
+		case !sameRule(old, r):
+			plan.add(Change{Action: ActionUpdate, CampaignID: r.CampaignID, Dimension: r.DimensionType})
+		default:
+			plan.Summary.Unchanged++
+			continue
+		}
+		plan.batch.SaveRules = append(plan.batch.SaveRules, r)
+	}
+
+	for _, r := range current.Rules {
+		key := repository.RuleKey{CampaignID: r.CampaignID, Dimension: r.DimensionType}
+		if imported[r.CampaignID] && !importedRules[key] {
+			plan.add(Change{Action: ActionDelete, CampaignID: r.CampaignID, Dimension: r.DimensionType})
+			plan.batch.DeleteRules = append(plan.batch.DeleteRules, key)
+		}
+	}
+
+	if prune {
+		for _, c := range current.Campaigns {
+			if !imported[c.ID] {
+				// Its rules go with it.
+				plan.add(Change{Action: ActionDelete, CampaignID: c.ID})
+				plan.batch.DeleteCampaigns = append(plan.batch.DeleteCampaigns, c.ID)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// changedFields lists the fields that differ between two versions of a
+// campaign, compared as they would be written to CSV.
+func changedFields(old, new models.Campaign) ([]string, error) {
+	oldCells, err := campaignCells(old)
+	if err != nil {
+		return nil, err
+	}
+	newCells, err := campaignCells(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, col := range campaignColumns {
+		if oldCells[col.name] != newCells[col.name] {
+			fields = append(fields, col.name)
+		}
+	}
+	return fields, nil
+}
+
+func sameRule(a, b models.TargetingRule) bool {
+	if a.RuleType != b.RuleType || a.MatchType.OrDefault() != b.MatchType.OrDefault() || len(a.Values) != len(b.Values) {
+		return false
+	}
+	for i := range a.Values {
+		if a.Values[i] != b.Values[i] {
+			return false
+		}
+	}
+	return true
+}
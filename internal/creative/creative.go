@@ -0,0 +1,125 @@
+// Package creative counts the impressions and clicks of campaign creatives
+// and picks which creative a delivery shows.
+package creative
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"targeting-engine/internal/models"
+)
+
+// Stats are a creative's delivered impressions and clicks.
+type Stats struct {
+	Impressions int64
+	Clicks      int64
+}
+
+// Store keeps creative counters.
+type Store interface {
+	// Stats returns the counters of a campaign's creatives by creative ID.
+	// Creatives never shown may be missing.
+	Stats(ctx context.Context, campaignID string) (map[string]Stats, error)
+	AddImpression(ctx context.Context, campaignID, creativeID string) error
+	AddClick(ctx context.Context, campaignID, creativeID string) error
+}
+
+// MemoryStore keeps counters in process memory, so CTR rotation starts
+// learning again on every restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	campaigns map[string]map[string]*Stats
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{campaigns: make(map[string]map[string]*Stats)}
+}
+
+func (s *MemoryStore) Stats(ctx context.Context, campaignID string) (map[string]Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]Stats, len(s.campaigns[campaignID]))
+	for id, st := range s.campaigns[campaignID] {
+		stats[id] = *st
+	}
+	return stats, nil
+}
+
+func (s *MemoryStore) AddImpression(ctx context.Context, campaignID, creativeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counters(campaignID, creativeID).Impressions++
+	return nil
+}
+
+func (s *MemoryStore) AddClick(ctx context.Context, campaignID, creativeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counters(campaignID, creativeID).Clicks++
+	return nil
+}
+
+// counters returns a creative's counters, creating them. s.mu must be held.
+func (s *MemoryStore) counters(campaignID, creativeID string) *Stats {
+	creatives, ok := s.campaigns[campaignID]
+	if !ok {
+		creatives = make(map[string]*Stats)
+		s.campaigns[campaignID] = creatives
+	}
+	st, ok := creatives[creativeID]
+	if !ok {
+		st = &Stats{}
+		creatives[creativeID] = st
+	}
+	return st
+}
+
+// Choose picks one of creatives, which must not be empty, by rotation.
+// random returns numbers in [0, 1). stats are only read by CTR rotation,
+// which shows every creative once and then the one with the best upper
+// confidence bound on its click-through rate (UCB1), so creatives that
+// have been shown little still get tried.
+func Choose(rotation models.Rotation, creatives []models.Creative, stats map[string]Stats, random func() float64) *models.Creative {
+	switch rotation {
+	case models.RotationWeighted:
+		total := 0
+		for i := range creatives {
+			total += creatives[i].RotationWeight()
+		}
+		target := random() * float64(total)
+		for i := range creatives {
+			target -= float64(creatives[i].RotationWeight())
+			if target < 0 {
+				return &creatives[i]
+			}
+		}
+		return &creatives[len(creatives)-1]
+
+	case models.RotationCTR:
+		var shown int64
+		for i := range creatives {
+			st := stats[creatives[i].ID]
+			if st.Impressions == 0 {
+				return &creatives[i]
+			}
+			shown += st.Impressions
+		}
+		best, bestScore := 0, math.Inf(-1)
+		for i := range creatives {
+			st := stats[creatives[i].ID]
+			n := float64(st.Impressions)
+			score := float64(st.Clicks)/n + math.Sqrt(2*math.Log(float64(shown))/n)
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		return &creatives[best]
+
+	default:
+		return &creatives[int(random()*float64(len(creatives)))]
+	}
+}
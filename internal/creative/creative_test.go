@@ -0,0 +1,76 @@
+package creative
+
+import (
+	"context"
+	"testing"
+
+	"targeting-engine/internal/models"
+)
+
+func TestChoose(t *testing.T) {
+	creatives := []models.Creative{{ID: "a", Weight: 3}, {ID: "b"}, {ID: "c"}}
+
+	tests := []struct {
+		name     string
+		rotation models.Rotation
+		random   float64
+		stats    map[string]Stats
+		want     string
+	}{
+		{name: "Even", rotation: models.RotationEven, random: 0.5, want: "b"},
+		{name: "Even by default", random: 0.99, want: "c"},
+		{name: "Weighted", rotation: models.RotationWeighted, random: 0.5, want: "a"},
+		{name: "Weighted past the heaviest", rotation: models.RotationWeighted, random: 0.7, want: "b"},
+		{
+			name:     "CTR tries unshown creatives first",
+			rotation: models.RotationCTR,
+			stats:    map[string]Stats{"a": {Impressions: 10, Clicks: 5}, "c": {Impressions: 10}},
+			want:     "b",
+		},
+		{
+			name:     "CTR prefers the best rate",
+			rotation: models.RotationCTR,
+			stats: map[string]Stats{
+				"a": {Impressions: 1000, Clicks: 10},
+				"b": {Impressions: 1000, Clicks: 50},
+				"c": {Impressions: 1000, Clicks: 20},
+			},
+			want: "b",
+		},
+		{
+			name:     "CTR explores rarely shown creatives",
+			rotation: models.RotationCTR,
+			stats: map[string]Stats{
+				"a": {Impressions: 5000, Clicks: 100},
+				"b": {Impressions: 5000, Clicks: 120},
+				"c": {Impressions: 2},
+			},
+			want: "c",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Choose(tc.rotation, creatives, tc.stats, func() float64 { return tc.random })
+			if got.ID != tc.want {
+				t.Errorf("Expected creative %s but got %s", tc.want, got.ID)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.AddImpression(ctx, "c1", "a")
+	store.AddImpression(ctx, "c1", "a")
+	store.AddClick(ctx, "c1", "a")
+	store.AddImpression(ctx, "c2", "a")
+
+	stats, err := store.Stats(ctx, "c1")
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if len(stats) != 1 || stats["a"] != (Stats{Impressions: 2, Clicks: 1}) {
+		t.Errorf("Expected 2 impressions and 1 click of c1's creative but got %+v", stats)
+	}
+}
@@ -0,0 +1,131 @@
+// Package fixtures reads campaigns, rules, expressions and creatives from
+// JSON or YAML files. The seed command and the file repository share it.
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"targeting-engine/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures are campaigns together with their targeting rules, expressions
+// and creatives.
+type Fixtures struct {
+	Campaigns   []models.Campaign            `json:"campaigns"`
+	Rules       []models.TargetingRule       `json:"rules,omitempty"`
+	Expressions []models.TargetingExpression `json:"expressions,omitempty"`
+	Creatives   []models.Creative            `json:"creatives,omitempty"`
+}
+
+// Load reads and validates the fixtures in a JSON or YAML file.
+func Load(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fixtures: %w", err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing fixtures %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse decodes and validates JSON or YAML fixtures. Fields are named as in
+// the admin API, and unknown fields are rejected.
+func Parse(data []byte) (*Fixtures, error) {
+	// JSON is valid YAML, so one decoder reads both formats. The result goes
+	// through encoding/json so the models' JSON names and decoders apply.
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixtures
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&f); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Validate checks every fixture, and that rules, expressions and creatives
+// belong to a campaign in the same fixtures.
+func (f *Fixtures) Validate() error {
+	campaigns := make(map[string]bool, len(f.Campaigns))
+	for i := range f.Campaigns {
+		c := &f.Campaigns[i]
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("campaign %d: %w", i+1, err)
+		}
+		if campaigns[c.ID] {
+			return fmt.Errorf("campaign %s is listed twice", c.ID)
+		}
+		campaigns[c.ID] = true
+	}
+
+	rules := make(map[string]bool, len(f.Rules))
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if !campaigns[r.CampaignID] {
+			return fmt.Errorf("rule %d: campaign %s is not in the fixtures", i+1, r.CampaignID)
+		}
+		key := r.CampaignID + "/" + string(r.DimensionType)
+		if rules[key] {
+			return fmt.Errorf("campaign %s has two %s rules", r.CampaignID, r.DimensionType)
+		}
+		rules[key] = true
+	}
+
+	expressions := make(map[string]bool, len(f.Expressions))
+	for i := range f.Expressions {
+		e := &f.Expressions[i]
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("expression %d: %w", i+1, err)
+		}
+		if !campaigns[e.CampaignID] {
+			return fmt.Errorf("expression %d: campaign %s is not in the fixtures", i+1, e.CampaignID)
+		}
+		if expressions[e.CampaignID] {
+			return fmt.Errorf("campaign %s has two expressions", e.CampaignID)
+		}
+		expressions[e.CampaignID] = true
+	}
+	creatives := make(map[string]bool, len(f.Creatives))
+	for i := range f.Creatives {
+		c := &f.Creatives[i]
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("creative %d: %w", i+1, err)
+		}
+		if !campaigns[c.CampaignID] {
+			return fmt.Errorf("creative %d: campaign %s is not in the fixtures", i+1, c.CampaignID)
+		}
+		key := c.CampaignID + "/" + c.ID
+		if creatives[key] {
+			return fmt.Errorf("campaign %s has two creatives %s", c.CampaignID, c.ID)
+		}
+		creatives[key] = true
+	}
+	return nil
+}
+
+// Store is implemented by repositories that can upsert fixtures.
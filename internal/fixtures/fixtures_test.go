@@ -0,0 +1,81 @@
+package fixtures
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "YAML",
+			data: `
+campaigns:
+  - {id: a, name: A, image_url: https://a, cta: Go, status: ACTIVE, start_at: 2024-01-01T00:00:00Z}
+rules:
+  - {campaign_id: a, dimension_type: COUNTRY, rule_type: INCLUDE, values: [US]}
+`,
+		},
+		{
+			name: "JSON",
+			data: `{"campaigns": [{"id": "a", "name": "A", "image_url": "https://a", "cta": "Go", "status": "ACTIVE"}],
+				"expressions": [{"campaign_id": "a", "expression": {"dimension": "OS", "values": ["iOS"]}}]}`,
+		},
+		{
+			name: "Empty",
+			data: "",
+		},
+		{
+			name:    "Unknown field",
+			data:    `campaigns: [{id: a, name: A, image_url: https://a, cta: Go, status: ACTIVE, color: red}]`,
+			wantErr: "unknown field",
+		},
+		{
+			name:    "Invalid campaign",
+			data:    `campaigns: [{id: a, name: A, image_url: https://a, cta: Go, status: PAUSED}]`,
+			wantErr: "campaign 1: invalid status",
+		},
+		{
+			name:    "Rule of a missing campaign",
+			data:    `rules: [{campaign_id: a, dimension_type: OS, rule_type: INCLUDE, values: [iOS]}]`,
+			wantErr: "not in the fixtures",
+		},
+		{
+			name: "Duplicate campaign",
+			data: `
+campaigns:
+  - {id: a, name: A, image_url: https://a, cta: Go, status: ACTIVE}
+  - {id: a, name: B, image_url: https://b, cta: Go, status: ACTIVE}
+`,
+			wantErr: "listed twice",
+		},
+		{
+			name: "Creative without a URL",
+			data: `
+campaigns:
+  - {id: a, name: A, image_url: https://a, cta: Go, status: ACTIVE}
+creatives:
+  - {campaign_id: a, id: banner, type: IMAGE}
+`,
+			wantErr: "creative 1:",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected an error containing %q but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package frequency tracks how often devices have been shown each campaign so
+// delivery can enforce per-device frequency caps.
+package frequency
+
+import (
+	"context"
+	"time"
+)
+
+// Retention is how long exposures are kept. It covers the longest cap
+// period campaigns can use.
+const Retention = 7 * 24 * time.Hour
+
+// Cap allows at most Max exposures of a campaign to one device per Period.
+type Cap struct {
+	Max    int
+	Period time.Duration
+}
+
+// Store records campaign exposures per device.
+type Store interface {
+	// Exposures returns, for each of campaignIDs the device has seen at or
+	// after since, the times it was shown.
+	Exposures(ctx context.Context, deviceID string, campaignIDs []string, since time.Time) (map[string][]time.Time, error)
+	// Record notes that the device was shown each campaign in caps at at,
+	// unless that would exceed one of the campaign's caps. The check and the
+	// write are one atomic step, so concurrent requests can't overshoot a
+	// cap. It returns the campaigns that were recorded.
+	Record(ctx context.Context, deviceID string, caps map[string][]Cap, at time.Time) (map[string]bool, error)
+}
+
+// Allows reports whether showing a campaign at now stays within every one of
+// caps, given the times it was already shown.
+func Allows(caps []Cap, shown []time.Time, now time.Time) bool {
+	for _, c := range caps {
+		since := now.Add(-c.Period)
+		count := 0
+		for _, at := range shown {
+			if !at.Before(since) {
+				count++
+			}
+		}
+		if count >= c.Max {
+			return false
+		}
+	}
+	return true
+}
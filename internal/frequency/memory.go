@@ -0,0 +1,117 @@
+package frequency
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const shardCount = 64
+
+// MemoryStore keeps exposures in process memory. Devices are spread over
+// shards, each an LRU that forgets its least recently seen devices once it
+// holds more than its share of maxDevices.
+type MemoryStore struct {
+	shards [shardCount]*shard
+}
+
+type shard struct {
+	mu       sync.Mutex
+	capacity int
+	lru      *list.List
+	devices  map[string]*list.Element
+}
+
+type device struct {
+	id        string
+	exposures map[string][]time.Time
+}
+
+func NewMemoryStore(maxDevices int) *MemoryStore {
+	capacity := maxDevices / shardCount
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	s := &MemoryStore{}
+	for i := range s.shards {
+		s.shards[i] = &shard{
+			capacity: capacity,
+			lru:      list.New(),
+			devices:  make(map[string]*list.Element),
+		}
+	}
+	return s
+}
+
+func (s *MemoryStore) shardFor(deviceID string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(deviceID))
+	return s.shards[h.Sum32()%shardCount]
+}
+
+func (s *MemoryStore) Exposures(ctx context.Context, deviceID string, campaignIDs []string, since time.Time) (map[string][]time.Time, error) {
+	sh := s.shardFor(deviceID)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	elem, ok := sh.devices[deviceID]
+	if !ok {
+		return nil, nil
+	}
+	sh.lru.MoveToFront(elem)
+	d := elem.Value.(*device)
+
+	result := make(map[string][]time.Time)
+	for _, id := range campaignIDs {
+		for _, at := range d.exposures[id] {
+			if !at.Before(since) {
+				result[id] = append(result[id], at)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) Record(ctx context.Context, deviceID string, caps map[string][]Cap, at time.Time) (map[string]bool, error) {
+	sh := s.shardFor(deviceID)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	elem, ok := sh.devices[deviceID]
+	if ok {
+		sh.lru.MoveToFront(elem)
+	} else {
+		elem = sh.lru.PushFront(&device{id: deviceID, exposures: make(map[string][]time.Time)})
+		sh.devices[deviceID] = elem
+		for sh.lru.Len() > sh.capacity {
+			oldest := sh.lru.Back()
+			sh.lru.Remove(oldest)
+			delete(sh.devices, oldest.Value.(*device).id)
+		}
+	}
+
+	d := elem.Value.(*device)
+	cutoff := at.Add(-Retention)
+	recorded := make(map[string]bool, len(caps))
+	for id, campaignCaps := range caps {
+		shown := trimBefore(d.exposures[id], cutoff)
+		if Allows(campaignCaps, shown, at) {
+			shown = append(shown, at)
+			recorded[id] = true
+		}
+		d.exposures[id] = shown
+	}
+	return recorded, nil
+}
+
+// trimBefore drops the exposures older than cutoff from the sorted times.
+func trimBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
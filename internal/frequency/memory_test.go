@@ -0,0 +1,109 @@
+package frequency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// uncapped returns caps that let every campaign be recorded.
+func uncapped(campaignIDs ...string) map[string][]Cap {
+	caps := make(map[string][]Cap)
+	for _, id := range campaignIDs {
+		caps[id] = nil
+	}
+	return caps
+}
+
+func TestMemoryStoreExposures(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(1000)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	store.Record(ctx, "device", uncapped("a", "b"), now.Add(-2*time.Hour))
+	store.Record(ctx, "device", uncapped("a"), now.Add(-time.Minute))
+	store.Record(ctx, "other", uncapped("a"), now)
+
+	exposures, err := store.Exposures(ctx, "device", []string{"a", "b", "c"}, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(exposures["a"]) != 1 || len(exposures["b"]) != 0 || len(exposures["c"]) != 0 {
+		t.Errorf("Expected one recent exposure of a but got %v", exposures)
+	}
+
+	// Exposures older than the retention are dropped on the next record.
+	store.Record(ctx, "device", uncapped("b"), now.Add(Retention))
+	exposures, _ = store.Exposures(ctx, "device", []string{"b"}, time.Time{})
+	if len(exposures["b"]) != 1 {
+		t.Errorf("Expected expired exposures to be trimmed but got %v", exposures["b"])
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentDevices(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(shardCount)
+	now := time.Now()
+
+	for i := 0; i < shardCount*20; i++ {
+		store.Record(ctx, fmt.Sprintf("device-%d", i), uncapped("a"), now)
+	}
+
+	remembered := 0
+	for _, sh := range store.shards {
+		if sh.lru.Len() > sh.capacity {
+			t.Fatalf("Shard holds %d devices, more than its capacity %d", sh.lru.Len(), sh.capacity)
+		}
+		remembered += sh.lru.Len()
+	}
+	if remembered > shardCount {
+		t.Errorf("Expected at most %d devices but %d are remembered", shardCount, remembered)
+	}
+
+	last := fmt.Sprintf("device-%d", shardCount*20-1)
+	if exposures, _ := store.Exposures(ctx, last, []string{"a"}, time.Time{}); len(exposures["a"]) != 1 {
+		t.Errorf("Expected the most recent device to be remembered")
+	}
+}
+
+func TestMemoryStoreRecordEnforcesCaps(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(1000)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	caps := map[string][]Cap{"a": {{Max: 3, Period: time.Hour}}, "b": nil}
+
+	// Concurrent records can't overshoot the cap.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	recordedA := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorded, err := store.Record(ctx, "device", caps, now)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if !recorded["b"] {
+				t.Errorf("Expected the uncapped campaign to be recorded but got %v", recorded)
+			}
+			mu.Lock()
+			if recorded["a"] {
+				recordedA++
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if recordedA != 3 {
+		t.Errorf("Expected a to be recorded 3 times but it was recorded %d times", recordedA)
+	}
+
+	// The cap frees up once its period has passed.
+	if recorded, _ := store.Record(ctx, "device", caps, now.Add(time.Hour+time.Second)); !recorded["a"] {
+		t.Errorf("Expected the cap to reset after its period but got %v", recorded)
+	}
+}
@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"targeting-engine/internal/bulk"
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+	"targeting-engine/internal/service"
+)
+
+// maxAdminBodyBytes caps the size of admin request bodies.
+const maxAdminBodyBytes = 1 << 20
+
+// maxImportBodyBytes caps the size of bulk imports.
+const maxImportBodyBytes = 32 << 20
+
+type AdminHandler struct {
+	service service.AdminService
+	mux     *http.ServeMux
+}
+
+// NewAdminHandler serves the campaign, targeting rule and creative CRUD API
+// under /v1/admin/.
+func NewAdminHandler(service service.AdminService) http.Handler {
+	h := &AdminHandler{
+		service: service,
+		mux:     http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /v1/admin/campaigns", h.listCampaigns)
+	h.mux.HandleFunc("POST /v1/admin/campaigns", h.createCampaign)
+	h.mux.HandleFunc("GET /v1/admin/campaigns/{id}", h.getCampaign)
+	h.mux.HandleFunc("PUT /v1/admin/campaigns/{id}", h.updateCampaign)
+	h.mux.HandleFunc("PATCH /v1/admin/campaigns/{id}", h.patchCampaign)
+	h.mux.HandleFunc("DELETE /v1/admin/campaigns/{id}", h.deleteCampaign)
+
+	h.mux.HandleFunc("GET /v1/admin/campaigns/{id}/rules", h.listRules)
+	h.mux.HandleFunc("POST /v1/admin/campaigns/{id}/rules", h.createRule)
+	h.mux.HandleFunc("GET /v1/admin/campaigns/{id}/rules/{dimension}", h.getRule)
+	h.mux.HandleFunc("PUT /v1/admin/campaigns/{id}/rules/{dimension}", h.updateRule)
+	h.mux.HandleFunc("DELETE /v1/admin/campaigns/{id}/rules/{dimension}", h.deleteRule)
+
+	h.mux.HandleFunc("GET /v1/admin/campaigns/{id}/expression", h.getExpression)
+	h.mux.HandleFunc("PUT /v1/admin/campaigns/{id}/expression", h.setExpression)
+	h.mux.HandleFunc("DELETE /v1/admin/campaigns/{id}/expression", h.deleteExpression)
+
+	h.mux.HandleFunc("GET /v1/admin/campaigns/{id}/creatives", h.listCreatives)
+	h.mux.HandleFunc("POST /v1/admin/campaigns/{id}/creatives", h.createCreative)
+	h.mux.HandleFunc("GET /v1/admin/campaigns/{id}/creatives/{creative}", h.getCreative)
+	h.mux.HandleFunc("PUT /v1/admin/campaigns/{id}/creatives/{creative}", h.updateCreative)
+	h.mux.HandleFunc("DELETE /v1/admin/campaigns/{id}/creatives/{creative}", h.deleteCreative)
+
+	h.mux.HandleFunc("GET /v1/admin/export", h.exportCampaigns)
+	h.mux.HandleFunc("POST /v1/admin/import", h.importCampaigns)
+
+	return h
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *AdminHandler) listCampaigns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.CampaignFilter{
+		Status: models.Status(strings.ToUpper(query.Get("status"))),
+		Name:   query.Get("name"),
+	}
+
+	var err error
+	if filter.Limit, err = intParam(query.Get("limit")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid limit param")
+		return
+	}
+	if filter.Offset, err = intParam(query.Get("offset")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid offset param")
+		return
+	}
+
+	campaigns, err := h.service.ListCampaigns(r.Context(), filter)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	if campaigns == nil {
+		campaigns = []models.Campaign{}
+	}
+	respondWithJSON(w, http.StatusOK, campaigns)
+}
+
+func (h *AdminHandler) createCampaign(w http.ResponseWriter, r *http.Request) {
+	var campaign models.Campaign
+	if !decodeBody(w, r, &campaign) {
+		return
+	}
+
+	created, err := h.service.CreateCampaign(r.Context(), campaign)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/admin/campaigns/"+created.ID)
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+func (h *AdminHandler) getCampaign(w http.ResponseWriter, r *http.Request) {
+	campaign, err := h.service.GetCampaign(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, campaign)
+}
+
+func (h *AdminHandler) updateCampaign(w http.ResponseWriter, r *http.Request) {
+	var campaign models.Campaign
+	if !decodeBody(w, r, &campaign) {
+		return
+	}
+	if campaign.ID != "" && campaign.ID != r.PathValue("id") {
+		respondWithError(w, http.StatusBadRequest, "campaign id in body does not match the URL")
+		return
+	}
+	campaign.ID = r.PathValue("id")
+
+	updated, err := h.service.UpdateCampaign(r.Context(), campaign)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+func (h *AdminHandler) patchCampaign(w http.ResponseWriter, r *http.Request) {
+	var patch models.CampaignPatch
+	if !decodeBody(w, r, &patch) {
+		return
+	}
+
+	updated, err := h.service.PatchCampaign(r.Context(), r.PathValue("id"), patch)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+func (h *AdminHandler) deleteCampaign(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteCampaign(r.Context(), r.PathValue("id")); err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) listRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListRules(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	if rules == nil {
+		rules = []models.TargetingRule{}
+	}
+	respondWithJSON(w, http.StatusOK, rules)
+}
+
+func (h *AdminHandler) createRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.TargetingRule
+	if !decodeBody(w, r, &rule) {
+		return
+	}
+	if rule.CampaignID != "" && rule.CampaignID != r.PathValue("id") {
+		respondWithError(w, http.StatusBadRequest, "campaign id in body does not match the URL")
+		return
+	}
+	rule.CampaignID = r.PathValue("id")
+	rule.DimensionType = models.DimensionType(strings.ToUpper(string(rule.DimensionType)))
+	rule.RuleType = models.RuleType(strings.ToUpper(string(rule.RuleType)))
+	rule.MatchType = models.MatchType(strings.ToUpper(string(rule.MatchType)))
+
+	created, err := h.service.CreateRule(r.Context(), rule)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/admin/campaigns/"+created.CampaignID+"/rules/"+string(created.DimensionType))
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+func (h *AdminHandler) getRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.service.GetRule(r.Context(), r.PathValue("id"), dimensionParam(r))
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rule)
+}
+
+func (h *AdminHandler) updateRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.TargetingRule
+	if !decodeBody(w, r, &rule) {
+		return
+	}
+	dimension := dimensionParam(r)
+	if rule.DimensionType != "" && models.DimensionType(strings.ToUpper(string(rule.DimensionType))) != dimension {
+		respondWithError(w, http.StatusBadRequest, "dimension_type in body does not match the URL")
+		return
+	}
+	rule.CampaignID = r.PathValue("id")
+	rule.DimensionType = dimension
+	rule.RuleType = models.RuleType(strings.ToUpper(string(rule.RuleType)))
+	rule.MatchType = models.MatchType(strings.ToUpper(string(rule.MatchType)))
+
+	updated, err := h.service.UpdateRule(r.Context(), rule)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+func (h *AdminHandler) deleteRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteRule(r.Context(), r.PathValue("id"), dimensionParam(r)); err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) getExpression(w http.ResponseWriter, r *http.Request) {
+	expr, err := h.service.GetExpression(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, expr.Expression)
+}
+
+func (h *AdminHandler) setExpression(w http.ResponseWriter, r *http.Request) {
+	expr := models.TargetingExpression{CampaignID: r.PathValue("id")}
+	if !decodeBody(w, r, &expr.Expression) {
+		return
+	}
+
+	saved, err := h.service.SetExpression(r.Context(), expr)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, saved.Expression)
+}
+
+func (h *AdminHandler) deleteExpression(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteExpression(r.Context(), r.PathValue("id")); err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) listCreatives(w http.ResponseWriter, r *http.Request) {
+	creatives, err := h.service.ListCreatives(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	if creatives == nil {
+		creatives = []models.Creative{}
+	}
+	respondWithJSON(w, http.StatusOK, creatives)
+}
+
+func (h *AdminHandler) createCreative(w http.ResponseWriter, r *http.Request) {
+	var creative models.Creative
+	if !decodeBody(w, r, &creative) {
+		return
+	}
+	if creative.CampaignID != "" && creative.CampaignID != r.PathValue("id") {
+		respondWithError(w, http.StatusBadRequest, "campaign id in body does not match the URL")
+		return
+	}
+	creative.CampaignID = r.PathValue("id")
+	creative.Type = models.CreativeType(strings.ToUpper(string(creative.Type)))
+
+	created, err := h.service.CreateCreative(r.Context(), creative)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/admin/campaigns/"+created.CampaignID+"/creatives/"+created.ID)
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+func (h *AdminHandler) getCreative(w http.ResponseWriter, r *http.Request) {
+	creative, err := h.service.GetCreative(r.Context(), r.PathValue("id"), r.PathValue("creative"))
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, creative)
+}
+
+func (h *AdminHandler) updateCreative(w http.ResponseWriter, r *http.Request) {
+	var creative models.Creative
+	if !decodeBody(w, r, &creative) {
+		return
+	}
+	if (creative.CampaignID != "" && creative.CampaignID != r.PathValue("id")) ||
+		(creative.ID != "" && creative.ID != r.PathValue("creative")) {
+		respondWithError(w, http.StatusBadRequest, "creative in body does not match the URL")
+		return
+	}
+	creative.CampaignID = r.PathValue("id")
+	creative.ID = r.PathValue("creative")
+	creative.Type = models.CreativeType(strings.ToUpper(string(creative.Type)))
+
+	updated, err := h.service.UpdateCreative(r.Context(), creative)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+func (h *AdminHandler) deleteCreative(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteCreative(r.Context(), r.PathValue("id"), r.PathValue("creative")); err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) exportCampaigns(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.service.Export(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := bulk.Write(&body, format, data); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", formatContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="campaigns.`+string(format)+`"`)
+	w.Write(body.Bytes())
+}
+
+func (h *AdminHandler) importCampaigns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("format")
+	if name == "" && strings.HasPrefix(r.Header.Get("Content-Type"), formatContentTypes[bulk.FormatCSV]) {
+		name = string(bulk.FormatCSV)
+	}
+	format, err := bulk.ParseFormat(name)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var opts service.ImportOptions
+	if opts.DryRun, err = boolParam(query.Get("dry_run")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid dry_run param")
+		return
+	}
+	if opts.Prune, err = boolParam(query.Get("prune")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid prune param")
+		return
+	}
+
+	data, err := bulk.Read(http.MaxBytesReader(w, r.Body, maxImportBodyBytes), format)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid import: "+err.Error())
+		return
+	}
+
+	plan, err := h.service.Import(r.Context(), data, opts)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, plan)
+}
+
+var formatContentTypes = map[bulk.Format]string{
+	bulk.FormatJSON: "application/json",
+	bulk.FormatCSV:  "text/csv",
+}
+
+func dimensionParam(r *http.Request) models.DimensionType {
+	return models.DimensionType(strings.ToUpper(r.PathValue("dimension")))
+}
+
+func intParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New("must be a non-negative integer")
+	}
+	return n, nil
+}
+
+func boolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// decodeBody decodes a JSON request body into v, answering 400 when it can't.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// respondWithServiceError maps service and repository errors to status codes.
+func respondWithServiceError(w http.ResponseWriter, err error) {
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrCampaignNotFound), errors.Is(err, repository.ErrRuleNotFound),
+		errors.Is(err, repository.ErrExpressionNotFound), errors.Is(err, repository.ErrCreativeNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repository.ErrCampaignExists), errors.Is(err, repository.ErrRuleExists),
+		errors.Is(err, repository.ErrCreativeExists):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, repository.ErrReadOnly):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func respondWithJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+func respondWithError(w http.ResponseWriter, statusCode int, message string) {
+	respondWithJSON(w, statusCode, models.ErrorResponse{Error: message})
+}
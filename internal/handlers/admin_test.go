@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+	"targeting-engine/internal/seed"
+	"targeting-engine/internal/service"
+)
+
+const netflix = `{"id":"netflix","name":"Netflix","image_url":"https://netflix","cta":"Watch"}`
+
+func TestAdminServeHTTP(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	if err := repo.ApplyBatch(context.Background(), repository.FixturesBatch(seed.Demo())); err != nil {
+		t.Fatalf("Failed to initialize test data: %v", err)
+	}
+	handler := NewAdminHandler(service.NewCampaignService(repo))
+
+	// The cases run in order against the same repository, so later ones see
+	// what earlier ones wrote.
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+	}{
+		{"List campaigns", http.MethodGet, "/v1/admin/campaigns?status=active&limit=2&offset=1", "", http.StatusOK},
+		{"List campaigns with invalid limit", http.MethodGet, "/v1/admin/campaigns?limit=ten", "", http.StatusBadRequest},
+		{"List campaigns with negative offset", http.MethodGet, "/v1/admin/campaigns?offset=-1", "", http.StatusBadRequest},
+		{"Create campaign", http.MethodPost, "/v1/admin/campaigns", netflix, http.StatusCreated},
+		{"Create existing campaign", http.MethodPost, "/v1/admin/campaigns", netflix, http.StatusConflict},
+		{"Create invalid campaign", http.MethodPost, "/v1/admin/campaigns", `{"id":"hulu"}`, http.StatusBadRequest},
+		{"Create campaign with unknown field", http.MethodPost, "/v1/admin/campaigns", `{"id":"hulu","budget":5}`, http.StatusBadRequest},
+		{"Create campaign with malformed body", http.MethodPost, "/v1/admin/campaigns", `{"id":`, http.StatusBadRequest},
+		{"Get campaign", http.MethodGet, "/v1/admin/campaigns/netflix", "", http.StatusOK},
+		{"Get unknown campaign", http.MethodGet, "/v1/admin/campaigns/hulu", "", http.StatusNotFound},
+		{"Update campaign", http.MethodPut, "/v1/admin/campaigns/netflix", `{"name":"Netflix","image_url":"https://netflix","cta":"Watch","status":"INACTIVE"}`, http.StatusOK},
+		{"Update campaign with mismatched id", http.MethodPut, "/v1/admin/campaigns/hulu", netflix, http.StatusBadRequest},
+		{"Update unknown campaign", http.MethodPut, "/v1/admin/campaigns/hulu", `{"name":"Hulu","image_url":"https://hulu","cta":"Watch","status":"ACTIVE"}`, http.StatusNotFound},
+		{"Patch campaign", http.MethodPatch, "/v1/admin/campaigns/netflix", `{"name":"Netflix Originals"}`, http.StatusOK},
+		{"Patch campaign with unknown field", http.MethodPatch, "/v1/admin/campaigns/netflix", `{"title":"Netflix"}`, http.StatusBadRequest},
+		{"Patch campaign invalidly", http.MethodPatch, "/v1/admin/campaigns/netflix", `{"name":""}`, http.StatusBadRequest},
+		{"Patch unknown campaign", http.MethodPatch, "/v1/admin/campaigns/hulu", `{"name":"Hulu"}`, http.StatusNotFound},
+
+		{"Create rule", http.MethodPost, "/v1/admin/campaigns/netflix/rules", `{"dimension_type":"country","rule_type":"include","values":["US"]}`, http.StatusCreated},
+		{"Create existing rule", http.MethodPost, "/v1/admin/campaigns/netflix/rules", `{"dimension_type":"COUNTRY","rule_type":"EXCLUDE","values":["CA"]}`, http.StatusConflict},
+		{"Create invalid rule", http.MethodPost, "/v1/admin/campaigns/netflix/rules", `{"dimension_type":"OS","rule_type":"INCLUDE","values":[]}`, http.StatusBadRequest},
+		{"Create rule with mismatched campaign", http.MethodPost, "/v1/admin/campaigns/netflix/rules", `{"campaign_id":"spotify","dimension_type":"OS","rule_type":"INCLUDE","values":["iOS"]}`, http.StatusBadRequest},
+		{"Create rule for unknown campaign", http.MethodPost, "/v1/admin/campaigns/hulu/rules", `{"dimension_type":"OS","rule_type":"INCLUDE","values":["iOS"]}`, http.StatusNotFound},
+		{"List rules", http.MethodGet, "/v1/admin/campaigns/netflix/rules", "", http.StatusOK},
+		{"List rules of unknown campaign", http.MethodGet, "/v1/admin/campaigns/hulu/rules", "", http.StatusNotFound},
+		{"Get rule", http.MethodGet, "/v1/admin/campaigns/netflix/rules/country", "", http.StatusOK},
+		{"Get unknown rule", http.MethodGet, "/v1/admin/campaigns/netflix/rules/os", "", http.StatusNotFound},
+		{"Update rule", http.MethodPut, "/v1/admin/campaigns/netflix/rules/COUNTRY", `{"rule_type":"EXCLUDE","values":["CA"]}`, http.StatusOK},
+		{"Update rule with mismatched dimension", http.MethodPut, "/v1/admin/campaigns/netflix/rules/COUNTRY", `{"dimension_type":"OS","rule_type":"EXCLUDE","values":["CA"]}`, http.StatusBadRequest},
+		{"Update unknown rule", http.MethodPut, "/v1/admin/campaigns/netflix/rules/OS", `{"rule_type":"INCLUDE","values":["iOS"]}`, http.StatusNotFound},
+		{"Delete rule", http.MethodDelete, "/v1/admin/campaigns/netflix/rules/COUNTRY", "", http.StatusNoContent},
+		{"Delete unknown rule", http.MethodDelete, "/v1/admin/campaigns/netflix/rules/COUNTRY", "", http.StatusNotFound},
+
+		{"Set expression", http.MethodPut, "/v1/admin/campaigns/netflix/expression", `{"dimension":"OS","values":["iOS"]}`, http.StatusOK},
+		{"Set invalid expression", http.MethodPut, "/v1/admin/campaigns/netflix/expression", `{"and":[]}`, http.StatusBadRequest},
+		{"Set expression with unknown field", http.MethodPut, "/v1/admin/campaigns/netflix/expression", `{"xor":[]}`, http.StatusBadRequest},
+		{"Get expression", http.MethodGet, "/v1/admin/campaigns/netflix/expression", "", http.StatusOK},
+		{"Delete expression", http.MethodDelete, "/v1/admin/campaigns/netflix/expression", "", http.StatusNoContent},
+		{"Get deleted expression", http.MethodGet, "/v1/admin/campaigns/netflix/expression", "", http.StatusNotFound},
+		{"Delete unknown expression", http.MethodDelete, "/v1/admin/campaigns/netflix/expression", "", http.StatusNotFound},
+
+		{"Create creative", http.MethodPost, "/v1/admin/campaigns/netflix/creatives", `{"id":"trailer","type":"video","url":"https://trailer.mp4"}`, http.StatusCreated},
+		{"Create existing creative", http.MethodPost, "/v1/admin/campaigns/netflix/creatives", `{"id":"trailer","type":"VIDEO","url":"https://trailer.mp4"}`, http.StatusConflict},
+		{"Create invalid creative", http.MethodPost, "/v1/admin/campaigns/netflix/creatives", `{"id":"banner","type":"IMAGE"}`, http.StatusBadRequest},
+		{"Create creative with mismatched campaign", http.MethodPost, "/v1/admin/campaigns/netflix/creatives", `{"campaign_id":"spotify","id":"banner","type":"IMAGE","url":"https://banner"}`, http.StatusBadRequest},
+		{"List creatives", http.MethodGet, "/v1/admin/campaigns/netflix/creatives", "", http.StatusOK},
+		{"Get creative", http.MethodGet, "/v1/admin/campaigns/netflix/creatives/trailer", "", http.StatusOK},
+		{"Get unknown creative", http.MethodGet, "/v1/admin/campaigns/netflix/creatives/banner", "", http.StatusNotFound},
+		{"Update creative", http.MethodPut, "/v1/admin/campaigns/netflix/creatives/trailer", `{"type":"VIDEO","url":"https://trailer.webm"}`, http.StatusOK},
+		{"Update creative with mismatched id", http.MethodPut, "/v1/admin/campaigns/netflix/creatives/trailer", `{"id":"banner","type":"VIDEO","url":"https://trailer.webm"}`, http.StatusBadRequest},
+		{"Update unknown creative", http.MethodPut, "/v1/admin/campaigns/netflix/creatives/banner", `{"type":"IMAGE","url":"https://banner"}`, http.StatusNotFound},
+		{"Delete creative", http.MethodDelete, "/v1/admin/campaigns/netflix/creatives/trailer", "", http.StatusNoContent},
+		{"Delete unknown creative", http.MethodDelete, "/v1/admin/campaigns/netflix/creatives/trailer", "", http.StatusNotFound},
+
+		{"Export", http.MethodGet, "/v1/admin/export?format=csv", "", http.StatusOK},
+		{"Export with unknown format", http.MethodGet, "/v1/admin/export?format=xml", "", http.StatusBadRequest},
+		{"Import dry run", http.MethodPost, "/v1/admin/import?dry_run=true&prune=1", `{"campaigns":[` + netflix + `]}`, http.StatusOK},
+		{"Import with invalid dry_run", http.MethodPost, "/v1/admin/import?dry_run=maybe", `{"campaigns":[]}`, http.StatusBadRequest},
+		{"Import with invalid prune", http.MethodPost, "/v1/admin/import?prune=yes", `{"campaigns":[]}`, http.StatusBadRequest},
+		{"Import malformed file", http.MethodPost, "/v1/admin/import", `{"campaigns":`, http.StatusBadRequest},
+		{"Import invalid campaign", http.MethodPost, "/v1/admin/import", `{"campaigns":[{"id":"hulu"}]}`, http.StatusBadRequest},
+
+		{"Delete campaign", http.MethodDelete, "/v1/admin/campaigns/netflix", "", http.StatusNoContent},
+		{"Delete unknown campaign", http.MethodDelete, "/v1/admin/campaigns/netflix", "", http.StatusNotFound},
+		{"Method not allowed", http.MethodPost, "/v1/admin/campaigns/spotify", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d but got %d: %s", tc.expectedStatus, rr.Code, rr.Body)
+			}
+		})
+	}
+}
+
+func TestAdminReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "campaigns.yaml")
+	if err := os.WriteFile(path, []byte("campaigns:\n  - {id: spotify, name: Spotify, image_url: https://a, cta: Download, status: ACTIVE}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := repository.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository failed: %v", err)
+	}
+	handler := NewAdminHandler(service.NewCampaignService(repo))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/v1/admin/campaigns", strings.NewReader(netflix)),
+		httptest.NewRequest(http.MethodDelete, "/v1/admin/campaigns/spotify", nil),
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d for %s %s but got %d: %s", http.StatusForbidden, req.Method, req.URL, rr.Code, rr.Body)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/admin/campaigns/spotify", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected reads to succeed but got %d: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestRespondWithServiceError(t *testing.T) {
+	tests := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{&models.ValidationError{Field: "name", Message: "must not be empty"}, http.StatusBadRequest},
+		{fmt.Errorf("line 3: %w", &models.ValidationError{Field: "id", Message: "must not be empty"}), http.StatusBadRequest},
+		{repository.ErrCampaignNotFound, http.StatusNotFound},
+		{repository.ErrRuleNotFound, http.StatusNotFound},
+		{repository.ErrExpressionNotFound, http.StatusNotFound},
+		{fmt.Errorf("campaign netflix: %w", repository.ErrCreativeNotFound), http.StatusNotFound},
+		{repository.ErrCampaignExists, http.StatusConflict},
+		{repository.ErrRuleExists, http.StatusConflict},
+		{repository.ErrCreativeExists, http.StatusConflict},
+		{repository.ErrReadOnly, http.StatusForbidden},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		rr := httptest.NewRecorder()
+		respondWithServiceError(rr, tc.err)
+		if rr.Code != tc.expectedStatus {
+			t.Errorf("Expected status code %d for %v but got %d", tc.expectedStatus, tc.err, rr.Code)
+		}
+		if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected Content-Type application/json but got %s", contentType)
+		}
+	}
+	rr := httptest.NewRecorder()
+	respondWithServiceError(rr, errors.New("password=secret"))
+	if strings.Contains(rr.Body.String(), "secret") {
+		t.Errorf("Expected internal errors not to be exposed but got %s", rr.Body)
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	for value, want := range map[string]int{"": 0, "0": 0, "25": 25} {
+		if got, err := intParam(value); err != nil || got != want {
+			t.Errorf("Expected intParam(%q) to be %d but got %d, %v", value, want, got, err)
+		}
+	}
+	for _, value := range []string{"-1", "ten", "1.5"} {
+		if _, err := intParam(value); err == nil {
+			t.Errorf("Expected intParam(%q) to fail", value)
+		}
+	}
+
+	for value, want := range map[string]bool{"": false, "true": true, "1": true, "false": false} {
+		if got, err := boolParam(value); err != nil || got != want {
+			t.Errorf("Expected boolParam(%q) to be %v but got %v, %v", value, want, got, err)
+		}
+	}
+	if _, err := boolParam("yes"); err == nil {
+		t.Error(`Expected boolParam("yes") to fail`)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"targeting-engine/internal/logging"
+	"targeting-engine/internal/repository"
+	"targeting-engine/internal/service"
+)
+
+type ClickHandler struct {
+	service service.ClickService
+}
+
+// NewClickHandler counts clicks on the creatives returned by the delivery
+// endpoint, identified by the cid and crid params.
+func NewClickHandler(service service.ClickService) http.Handler {
+	return &ClickHandler{service: service}
+}
+
+func (h *ClickHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	campaignID, creativeID := query.Get("cid"), query.Get("crid")
+	logging.AddFields(r.Context(), slog.String("campaign", campaignID), slog.String("creative", creativeID))
+	if campaignID == "" {
+		respondWithError(w, http.StatusBadRequest, "missing cid param")
+		return
+	}
+	if creativeID == "" {
+		respondWithError(w, http.StatusBadRequest, "missing crid param")
+		return
+	}
+
+	err := h.service.RecordClick(r.Context(), campaignID, creativeID)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, repository.ErrCreativeNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrNotReady):
+		respondWithError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
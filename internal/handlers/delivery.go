@@ -2,20 +2,40 @@ package handlers
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"strconv"
 
+	"targeting-engine/internal/logging"
+	"targeting-engine/internal/metrics"
 	"targeting-engine/internal/models"
 	"targeting-engine/internal/service"
+	"targeting-engine/internal/version"
 )
 
 type DeliveryHandler struct {
 	service service.Service
+	metrics *metrics.Metrics
 }
 
-func NewDeliveryHandler(service service.Service) http.Handler {
-	return &DeliveryHandler{
+// DeliveryOption customizes a DeliveryHandler.
+type DeliveryOption func(*DeliveryHandler)
+
+// WithMetrics records the fill rate and the campaigns delivered in m.
+func WithMetrics(m *metrics.Metrics) DeliveryOption {
+	return func(h *DeliveryHandler) {
+		h.metrics = m
+	}
+}
+
+func NewDeliveryHandler(service service.Service, opts ...DeliveryOption) http.Handler {
+	h := &DeliveryHandler{
 		service: service,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *DeliveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -24,23 +44,9 @@ func (h *DeliveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := r.URL.Query()
-	req := models.DeliveryRequest{
-		App:     query.Get("app"),
-		OS:      query.Get("os"),
-		Country: query.Get("country"),
-	}
-
-	if req.App == "" {
-		h.respondWithError(w, http.StatusBadRequest, "missing app param")
-		return
-	}
-	if req.OS == "" {
-		h.respondWithError(w, http.StatusBadRequest, "missing os param")
-		return
-	}
-	if req.Country == "" {
-		h.respondWithError(w, http.StatusBadRequest, "missing country param")
+	req, problem := parseDeliveryRequest(r)
+	if problem != "" {
+		h.respondWithError(w, http.StatusBadRequest, problem)
 		return
 	}
 	campaigns, err := h.service.GetMatchingCampaigns(r.Context(), req)
@@ -49,9 +55,29 @@ func (h *DeliveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h.respondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		if err == service.ErrNotReady {
+			h.respondWithError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
 		h.respondWithError(w, http.StatusInternalServerError, "internal server error")
 		return
 	}
+
+	campaignIDs := make([]string, len(campaigns))
+	var arms []string
+	for i, campaign := range campaigns {
+		campaignIDs[i] = campaign.CID
+		if campaign.Experiment != "" {
+			arms = append(arms, campaign.Experiment+"/"+campaign.Arm)
+		}
+	}
+	h.metrics.ObserveDelivery(campaignIDs)
+	logging.AddFields(r.Context(), slog.Any("campaigns", campaignIDs))
+	if len(arms) > 0 {
+		logging.AddFields(r.Context(), slog.Any("experiments", arms))
+	}
+	logging.Sample(r.Context())
+
 	if len(campaigns) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -67,3 +93,51 @@ func (h *DeliveryHandler) respondWithError(w http.ResponseWriter, statusCode int
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(models.ErrorResponse{Error: message})
 }
+
+// parseDeliveryRequest reads a delivery request from the query params. It
+// returns what is wrong with them, if anything, as a message for the client.
+func parseDeliveryRequest(r *http.Request) (models.DeliveryRequest, string) {
+	query := r.URL.Query()
+	req := models.DeliveryRequest{
+		App:        query.Get("app"),
+		OS:         query.Get("os"),
+		Country:    query.Get("country"),
+		OSVersion:  query.Get("os_version"),
+		AppVersion: query.Get("app_version"),
+		DeviceID:   query.Get("device_id"),
+		Size:       query.Get("size"),
+		Language:   query.Get("lang"),
+	}
+	logging.AddFields(r.Context(),
+		slog.String("app", req.App),
+		slog.String("os", req.OS),
+		slog.String("country", req.Country),
+	)
+
+	if req.App == "" {
+		return req, "missing app param"
+	}
+	if req.OS == "" {
+		return req, "missing os param"
+	}
+	if req.Country == "" {
+		return req, "missing country param"
+	}
+	if _, err := version.Parse(req.OSVersion); req.OSVersion != "" && err != nil {
+		return req, "invalid os_version param"
+	}
+	if _, err := version.Parse(req.AppVersion); req.AppVersion != "" && err != nil {
+		return req, "invalid app_version param"
+	}
+	if _, _, err := models.ParseSize(req.Size); req.Size != "" && err != nil {
+		return req, "invalid size param, must be WIDTHxHEIGHT"
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return req, "invalid limit param, must be a positive integer"
+		}
+		req.Limit = n
+	}
+	return req, ""
+}
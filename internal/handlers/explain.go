@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"targeting-engine/internal/service"
+)
+
+type ExplainHandler struct {
+	service service.ExplainService
+}
+
+// NewExplainHandler serves /v1/delivery/explain, which takes the delivery
+// endpoint's params and tells, for every campaign, which of its checks pass
+// for them and why.
+func NewExplainHandler(service service.ExplainService) http.Handler {
+	return &ExplainHandler{service: service}
+}
+
+func (h *ExplainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, problem := parseDeliveryRequest(r)
+	if problem != "" {
+		respondWithError(w, http.StatusBadRequest, problem)
+		return
+	}
+
+	explanation, err := h.service.Explain(r.Context(), req)
+	switch {
+	case err == nil:
+		respondWithJSON(w, http.StatusOK, explanation)
+	case errors.Is(err, service.ErrInvalidRequest):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrNotReady):
+		respondWithError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"targeting-engine/internal/repository"
+	"targeting-engine/internal/seed"
+	"targeting-engine/internal/service"
+)
+
+func TestExplainServeHTTP(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	if err := repo.ApplyBatch(ctx, repository.FixturesBatch(seed.Demo())); err != nil {
+		t.Fatalf("Failed to initialize test data: %v", err)
+	}
+
+	targetingService := service.NewTargetingService(repo)
+	notReady := httptest.NewRecorder()
+	NewExplainHandler(targetingService).ServeHTTP(notReady, httptest.NewRequest(http.MethodGet, "/v1/delivery/explain?app=a&country=US&os=Android", nil))
+	if notReady.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d before the first refresh but got %d", http.StatusServiceUnavailable, notReady.Code)
+	}
+	if err := targetingService.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to load targeting data: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		method         string
+		expectedStatus int
+		expectJSON     bool
+	}{
+		{
+			name:           "Method not allowed",
+			url:            "/v1/delivery/explain?app=com.example.app&country=US&os=Android",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectJSON:     false,
+		},
+		{
+			name:           "Missing app parameter",
+			url:            "/v1/delivery/explain?country=US&os=Android",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectJSON:     true,
+		},
+		{
+			name:           "Missing OS parameter",
+			url:            "/v1/delivery/explain?app=com.example.app&country=US",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectJSON:     true,
+		},
+		{
+			name:           "Missing country parameter",
+			url:            "/v1/delivery/explain?app=com.example.app&os=Android",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectJSON:     true,
+		},
+		{
+			name:           "Invalid size",
+			url:            "/v1/delivery/explain?app=com.example.app&country=US&os=Android&size=large",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectJSON:     true,
+		},
+		{
+			name:           "Invalid limit",
+			url:            "/v1/delivery/explain?app=com.example.app&country=US&os=Android&limit=0",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectJSON:     true,
+		},
+		{
+			name:           "User in US on Android",
+			url:            "/v1/delivery/explain?app=com.example.app&country=US&os=Android",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectJSON:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewExplainHandler(targetingService)
+			req, err := http.NewRequest(tc.method, tc.url, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d but got %d", tc.expectedStatus, rr.Code)
+			}
+
+			if tc.expectJSON {
+				contentType := rr.Header().Get("Content-Type")
+				if contentType != "application/json" {
+					t.Errorf("Expected Content-Type application/json but got %s", contentType)
+				}
+			}
+		})
+	}
+}
+
+func TestExplainResponse(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	if err := repo.ApplyBatch(ctx, repository.FixturesBatch(seed.Demo())); err != nil {
+		t.Fatalf("Failed to initialize test data: %v", err)
+	}
+	targetingService := service.NewTargetingService(repo)
+	if err := targetingService.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to load targeting data: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/delivery/explain?app=com.example.app&country=US&os=Android", nil)
+	NewExplainHandler(targetingService).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+
+	// Decoded loosely, so the field names the CLI and clients rely on are
+	// checked rather than the Go types.
+	var body struct {
+		Request   map[string]interface{}   `json:"request"`
+		Campaigns []map[string]interface{} `json:"campaigns"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if body.Request["app"] != "com.example.app" || body.Request["country"] != "US" || body.Request["os"] != "Android" {
+		t.Errorf("Expected the request to be echoed but got %v", body.Request)
+	}
+
+	campaigns := make(map[string]map[string]interface{})
+	for _, c := range body.Campaigns {
+		for _, field := range []string{"cid", "status", "eligible", "checks"} {
+			if _, ok := c[field]; !ok {
+				t.Errorf("Expected campaign field %q in %v", field, c)
+			}
+		}
+		campaigns[c["cid"].(string)] = c
+	}
+	if len(campaigns) != 3 {
+		t.Fatalf("Expected every demo campaign to be explained but got %v", body.Campaigns)
+	}
+
+	if spotify := campaigns["spotify"]; spotify["eligible"] != true || spotify["reason"] != nil {
+		t.Errorf("Expected spotify to be eligible without a reason but got %v", spotify)
+	}
+	duolingo := campaigns["duolingo"]
+	if duolingo["eligible"] != false || duolingo["reason"] != `country "US" is excluded` {
+		t.Errorf("Expected duolingo to be excluded in the US but got %v", duolingo)
+	}
+
+	var country map[string]interface{}
+	for _, check := range duolingo["checks"].([]interface{}) {
+		if c := check.(map[string]interface{}); c["dimension"] == "COUNTRY" {
+			country = c
+		}
+	}
+	want := map[string]interface{}{
+		"check":         "rule",
+		"dimension":     "COUNTRY",
+		"rule_type":     "EXCLUDE",
+		"match_type":    "EXACT",
+		"values":        []interface{}{"US"},
+		"request_value": "US",
+		"pass":          false,
+		"reason":        `country "US" is excluded`,
+	}
+	if !reflect.DeepEqual(country, want) {
+		t.Errorf("Expected the country check %v but got %v", want, country)
+	}
+}
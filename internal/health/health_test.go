@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, handler http.Handler) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Expected a JSON report: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	var dbErr error
+	checker.Add("database", func(ctx context.Context) (string, error) { return "", dbErr })
+	checker.Add("targeting_data", func(ctx context.Context) (string, error) { return "in sync 1s ago", nil })
+
+	code, report := serve(t, checker.ReadyHandler())
+	if code != http.StatusOK || report.Status != StatusOK {
+		t.Fatalf("Expected ready but got %d %+v", code, report)
+	}
+	if report.Checks["targeting_data"].Detail != "in sync 1s ago" {
+		t.Errorf("Expected check details to be reported but got %+v", report.Checks)
+	}
+
+	dbErr = errors.New("connection refused")
+	code, report = serve(t, checker.ReadyHandler())
+	if code != http.StatusServiceUnavailable || report.Status != StatusFailing {
+		t.Fatalf("Expected not ready but got %d %+v", code, report)
+	}
+	if db := report.Checks["database"]; db.Status != StatusFailing || db.Error != "connection refused" {
+		t.Errorf("Expected the database check to fail but got %+v", db)
+	}
+	if report.Checks["targeting_data"].Status != StatusOK {
+		t.Errorf("Expected other checks to keep their own status but got %+v", report.Checks)
+	}
+}
+
+func TestReadinessTimesOut(t *testing.T) {
+	checker := NewChecker(10 * time.Millisecond)
+	checker.Add("database", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	code, _ := serve(t, checker.ReadyHandler())
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected a hanging check to fail readiness but got %d", code)
+	}
+}
+
+func TestDrainFailsReadinessOnly(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) (string, error) { return "", nil })
+	checker.Drain()
+
+	if code, report := serve(t, checker.ReadyHandler()); code != http.StatusServiceUnavailable || report.Checks["shutdown"].Status != StatusFailing {
+		t.Errorf("Expected readiness to fail while draining but got %d %+v", code, report)
+	}
+	if code, _ := serve(t, checker.LiveHandler()); code != http.StatusOK {
+		t.Errorf("Expected liveness to hold while draining but got %d", code)
+	}
+}
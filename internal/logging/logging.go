@@ -0,0 +1,99 @@
+// Package logging sets up structured logging and carries request-scoped log
+// fields through contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// New returns a logger writing format ("json" or "text") to w. Records below
+// level are dropped; pass a *slog.LevelVar to change it at runtime.
+func New(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.ToUpper(s)))
+	return level, err
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, which includes the request's
+// ID when ctx belongs to a request, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Fields collects attributes about a request while it is handled, for the
+// line logged once it has been served.
+type Fields struct {
+	mu      sync.Mutex
+	attrs   []slog.Attr
+	sampled bool
+}
+
+type fieldsKey struct{}
+
+// NewFieldsContext returns a copy of ctx to which handlers can add fields.
+func NewFieldsContext(ctx context.Context) (context.Context, *Fields) {
+	fields := &Fields{}
+	return context.WithValue(ctx, fieldsKey{}, fields), fields
+}
+
+// AddFields adds attributes to the request's log line. It does nothing when
+// ctx doesn't belong to a logged request.
+func AddFields(ctx context.Context, attrs ...slog.Attr) {
+	if fields, ok := ctx.Value(fieldsKey{}).(*Fields); ok {
+		fields.mu.Lock()
+		fields.attrs = append(fields.attrs, attrs...)
+		fields.mu.Unlock()
+	}
+}
+
+// Sample marks the request as one whose successful log line may be sampled,
+// because it is too frequent to log every time.
+func Sample(ctx context.Context) {
+	if fields, ok := ctx.Value(fieldsKey{}).(*Fields); ok {
+		fields.mu.Lock()
+		fields.sampled = true
+		fields.mu.Unlock()
+	}
+}
+
+// Attrs returns the attributes added so far.
+func (f *Fields) Attrs() []slog.Attr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]slog.Attr(nil), f.attrs...)
+}
+
+// Sampled reports whether Sample was called for the request.
+func (f *Fields) Sampled() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sampled
+}
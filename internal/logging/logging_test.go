@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		if got, err := ParseLevel(input); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+}
+
+func TestNewHonorsLevel(t *testing.T) {
+	var out bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+
+	logger, err := New(&out, "json", level)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept", "campaign", "spotify")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record but got %q: %v", out.String(), err)
+	}
+	if record["msg"] != "kept" || record["campaign"] != "spotify" {
+		t.Errorf("Unexpected record %v", record)
+	}
+
+	if _, err := New(&out, "xml", level); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestFields(t *testing.T) {
+	// Adding fields outside a request is a no-op.
+	AddFields(context.Background(), slog.String("app", "spotify"))
+	Sample(context.Background())
+
+	ctx, fields := NewFieldsContext(context.Background())
+	AddFields(ctx, slog.String("app", "spotify"))
+	AddFields(ctx, slog.String("country", "US"))
+	if attrs := fields.Attrs(); len(attrs) != 2 || attrs[1].Key != "country" {
+		t.Errorf("Unexpected fields %v", attrs)
+	}
+	if fields.Sampled() {
+		t.Error("Expected requests not to be sampled unless marked")
+	}
+	Sample(ctx)
+	if !fields.Sampled() {
+		t.Error("Expected the request to be marked for sampling")
+	}
+}
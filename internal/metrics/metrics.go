@@ -0,0 +1,171 @@
+// Package metrics exposes the service's Prometheus metrics.
+//
+// A nil *Metrics is valid and records nothing, so components can be built
+// without metrics in tests.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "targeting_engine"
+
+// Refresh kinds reported by ObserveRefresh.
+const (
+	RefreshFull        = "full"
+	RefreshIncremental = "incremental"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	deliveries      *prometheus.CounterVec
+	campaignMatches *prometheus.CounterVec
+
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+
+	refreshes       *prometheus.CounterVec
+	refreshDuration *prometheus.HistogramVec
+	cachedCampaigns prometheus.Gauge
+	lastRefreshTime prometheus.Gauge
+}
+
+// New creates the metrics on a registry of their own, together with the Go
+// runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by route, method and status code.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"route", "method", "status"}),
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deliveries_total",
+			Help:      "Delivery requests answered, by whether any campaign was returned (filled) or not (empty).",
+		}, []string{"result"}),
+		campaignMatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "campaign_deliveries_total",
+			Help:      "Times each campaign was returned by a delivery request.",
+		}, []string{"campaign"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Time taken by repository operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_query_errors_total",
+			Help:      "Repository operations that returned an error.",
+		}, []string{"operation"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_refreshes_total",
+			Help:      "Rebuilds of the targeting cache, by kind and result.",
+		}, []string{"kind", "result"}),
+		refreshDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cache_refresh_duration_seconds",
+			Help:      "Time taken to rebuild the targeting cache, by kind.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"kind"}),
+		cachedCampaigns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_campaigns",
+			Help:      "Campaigns in the current targeting cache.",
+		}),
+		lastRefreshTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_last_refresh_timestamp_seconds",
+			Help:      "Unix time of the last successful targeting cache rebuild.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.deliveries, m.campaignMatches,
+		m.queryDuration, m.queryErrors,
+		m.refreshes, m.refreshDuration, m.cachedCampaigns, m.lastRefreshTime,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records a served HTTP request. route is the pattern the
+// request was routed by, which keeps the label's cardinality bounded.
+func (m *Metrics) ObserveRequest(route, method string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpDuration.WithLabelValues(route, method, code).Observe(d.Seconds())
+}
+
+// ObserveDelivery records the campaigns a delivery request returned. The fill
+// rate is the share of deliveries with the "filled" result.
+func (m *Metrics) ObserveDelivery(campaignIDs []string) {
+	if m == nil {
+		return
+	}
+	if len(campaignIDs) == 0 {
+		m.deliveries.WithLabelValues("empty").Inc()
+		return
+	}
+	m.deliveries.WithLabelValues("filled").Inc()
+	for _, id := range campaignIDs {
+		m.campaignMatches.WithLabelValues(id).Inc()
+	}
+}
+
+// ObserveQuery records how long a repository operation took.
+func (m *Metrics) ObserveQuery(operation string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.queryDuration.WithLabelValues(operation).Observe(d.Seconds())
+	if err != nil {
+		m.queryErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveRefresh records a rebuild of the targeting cache, which holds
+// campaigns campaigns when it succeeded.
+func (m *Metrics) ObserveRefresh(kind string, d time.Duration, campaigns int, err error) {
+	if m == nil {
+		return
+	}
+	m.refreshDuration.WithLabelValues(kind).Observe(d.Seconds())
+	if err != nil {
+		m.refreshes.WithLabelValues(kind, "error").Inc()
+		return
+	}
+	m.refreshes.WithLabelValues(kind, "success").Inc()
+	m.cachedCampaigns.Set(float64(campaigns))
+	m.lastRefreshTime.SetToCurrentTime()
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+
+	m.ObserveRequest("/v1/delivery", "GET", 200, 3*time.Millisecond)
+	m.ObserveRequest("/v1/delivery", "GET", 204, time.Millisecond)
+	m.ObserveDelivery([]string{"spotify", "duolingo"})
+	m.ObserveDelivery(nil)
+	m.ObserveDelivery([]string{"spotify"})
+	m.ObserveQuery("GetCampaigns", time.Millisecond, nil)
+	m.ObserveQuery("GetCampaigns", time.Millisecond, errors.New("connection refused"))
+	m.ObserveRefresh(RefreshFull, time.Millisecond, 12, nil)
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("/v1/delivery", "GET", "204")); got != 1 {
+		t.Errorf("Expected one 204 request but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.deliveries.WithLabelValues("filled")); got != 2 {
+		t.Errorf("Expected two filled deliveries but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.deliveries.WithLabelValues("empty")); got != 1 {
+		t.Errorf("Expected one empty delivery but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.campaignMatches.WithLabelValues("spotify")); got != 2 {
+		t.Errorf("Expected spotify to be delivered twice but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.queryErrors.WithLabelValues("GetCampaigns")); got != 1 {
+		t.Errorf("Expected one query error but got %v", got)
+	}
+	if got := testutil.ToFloat64(m.cachedCampaigns); got != 12 {
+		t.Errorf("Expected 12 cached campaigns but got %v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), `targeting_engine_http_request_duration_seconds_bucket{method="GET",route="/v1/delivery",status="200"`) {
+		t.Errorf("Expected the latency histogram to be exposed, got:\n%s", body)
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.ObserveRequest("/", "GET", 200, time.Millisecond)
+	m.ObserveDelivery([]string{"spotify"})
+	m.ObserveQuery("GetCampaigns", time.Millisecond, nil)
+	m.ObserveRefresh(RefreshFull, time.Millisecond, 1, nil)
+}
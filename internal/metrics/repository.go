@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+// Repository wraps a repository.Repository and records the latency of every
+// call, labelled with the method name.
+type Repository struct {
+	repo    repository.Repository
+	metrics *Metrics
+}
+
+var _ repository.Repository = (*Repository)(nil)
+
+// InstrumentRepository returns repo with its calls recorded in m.
+func InstrumentRepository(repo repository.Repository, m *Metrics) *Repository {
+	return &Repository{repo: repo, metrics: m}
+}
+
+func (r *Repository) observe(operation string, start time.Time, err error) {
+	r.metrics.ObserveQuery(operation, time.Since(start), err)
+}
+
+func (r *Repository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	start := time.Now()
+	campaigns, err := r.repo.GetCampaigns(ctx)
+	r.observe("GetCampaigns", start, err)
+	return campaigns, err
+}
+
+func (r *Repository) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
+	start := time.Now()
+	campaign, err := r.repo.GetCampaignByID(ctx, id)
+	r.observe("GetCampaignByID", start, err)
+	return campaign, err
+}
+
+func (r *Repository) ListCampaigns(ctx context.Context, filter repository.CampaignFilter) ([]models.Campaign, error) {
+	start := time.Now()
+	campaigns, err := r.repo.ListCampaigns(ctx, filter)
+	r.observe("ListCampaigns", start, err)
+	return campaigns, err
+}
+
+func (r *Repository) CreateCampaign(ctx context.Context, campaign models.Campaign) error {
+	start := time.Now()
+	err := r.repo.CreateCampaign(ctx, campaign)
+	r.observe("CreateCampaign", start, err)
+	return err
+}
+
+func (r *Repository) UpdateCampaign(ctx context.Context, campaign models.Campaign) error {
+	start := time.Now()
+	err := r.repo.UpdateCampaign(ctx, campaign)
+	r.observe("UpdateCampaign", start, err)
+	return err
+}
+
+func (r *Repository) UpdateCampaignStatus(ctx context.Context, id string, status models.Status) error {
+	start := time.Now()
+	err := r.repo.UpdateCampaignStatus(ctx, id, status)
+	r.observe("UpdateCampaignStatus", start, err)
+	return err
+}
+
+func (r *Repository) DeleteCampaign(ctx context.Context, id string) error {
+	start := time.Now()
+	err := r.repo.DeleteCampaign(ctx, id)
+	r.observe("DeleteCampaign", start, err)
+	return err
+}
+
+func (r *Repository) GetTargetingRules(ctx context.Context) ([]models.TargetingRule, error) {
+	start := time.Now()
+	rules, err := r.repo.GetTargetingRules(ctx)
+	r.observe("GetTargetingRules", start, err)
+	return rules, err
+}
+
+func (r *Repository) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]models.TargetingRule, error) {
+	start := time.Now()
+	rules, err := r.repo.GetTargetingRulesByCampaignID(ctx, campaignID)
+	r.observe("GetTargetingRulesByCampaignID", start, err)
+	return rules, err
+}
+
+func (r *Repository) CreateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	start := time.Now()
+	err := r.repo.CreateTargetingRule(ctx, rule)
+	r.observe("CreateTargetingRule", start, err)
+	return err
+}
+
+func (r *Repository) UpdateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	start := time.Now()
+	err := r.repo.UpdateTargetingRule(ctx, rule)
+	r.observe("UpdateTargetingRule", start, err)
+	return err
+}
+
+func (r *Repository) DeleteTargetingRule(ctx context.Context, campaignID string, dimension models.DimensionType) error {
+	start := time.Now()
+	err := r.repo.DeleteTargetingRule(ctx, campaignID, dimension)
+	r.observe("DeleteTargetingRule", start, err)
+	return err
+}
+
+func (r *Repository) GetTargetingExpressions(ctx context.Context) ([]models.TargetingExpression, error) {
+	start := time.Now()
+	expressions, err := r.repo.GetTargetingExpressions(ctx)
+	r.observe("GetTargetingExpressions", start, err)
+	return expressions, err
+}
+
+func (r *Repository) GetTargetingExpressionByCampaignID(ctx context.Context, campaignID string) (*models.TargetingExpression, error) {
+	start := time.Now()
+	expr, err := r.repo.GetTargetingExpressionByCampaignID(ctx, campaignID)
+	r.observe("GetTargetingExpressionByCampaignID", start, err)
+	return expr, err
+}
+
+func (r *Repository) SaveTargetingExpression(ctx context.Context, expr models.TargetingExpression) error {
+	start := time.Now()
+	err := r.repo.SaveTargetingExpression(ctx, expr)
+	r.observe("SaveTargetingExpression", start, err)
+	return err
+}
+
+func (r *Repository) DeleteTargetingExpression(ctx context.Context, campaignID string) error {
+	start := time.Now()
+	err := r.repo.DeleteTargetingExpression(ctx, campaignID)
+	r.observe("DeleteTargetingExpression", start, err)
+	return err
+}
+
+func (r *Repository) GetCreatives(ctx context.Context) ([]models.Creative, error) {
+	start := time.Now()
+	creatives, err := r.repo.GetCreatives(ctx)
+	r.observe("GetCreatives", start, err)
+	return creatives, err
+}
+
+func (r *Repository) GetCreativesByCampaignID(ctx context.Context, campaignID string) ([]models.Creative, error) {
+	start := time.Now()
+	creatives, err := r.repo.GetCreativesByCampaignID(ctx, campaignID)
+	r.observe("GetCreativesByCampaignID", start, err)
+	return creatives, err
+}
+
+func (r *Repository) CreateCreative(ctx context.Context, creative models.Creative) error {
+	start := time.Now()
+	err := r.repo.CreateCreative(ctx, creative)
+	r.observe("CreateCreative", start, err)
+	return err
+}
+
+func (r *Repository) UpdateCreative(ctx context.Context, creative models.Creative) error {
+	start := time.Now()
+	err := r.repo.UpdateCreative(ctx, creative)
+	r.observe("UpdateCreative", start, err)
+	return err
+}
+
+func (r *Repository) DeleteCreative(ctx context.Context, campaignID, id string) error {
+	start := time.Now()
+	err := r.repo.DeleteCreative(ctx, campaignID, id)
+	r.observe("DeleteCreative", start, err)
+	return err
+}
+
+func (r *Repository) ApplyBatch(ctx context.Context, batch repository.Batch) error {
+	start := time.Now()
+	err := r.repo.ApplyBatch(ctx, batch)
+	r.observe("ApplyBatch", start, err)
+	return err
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := r.repo.Ping(ctx)
+	r.observe("Ping", start, err)
+	return err
+}
+
+func (r *Repository) Close(ctx context.Context) error {
+	return r.repo.Close(ctx)
+}
@@ -1,30 +1,62 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"targeting-engine/internal/logging"
 )
 
-func LoggingMiddleware(next http.Handler) http.Handler {
+// LoggingMiddleware logs one line per request, with the fields handlers added
+// through logging.AddFields. Handlers get a logger carrying the request ID,
+// and the trace ID when the request is traced, from logging.FromContext.
+//
+// Successful requests marked with logging.Sample are only logged at
+// sampleRate, read on every request so it can change at runtime.
+func LoggingMiddleware(logger *slog.Logger, sampleRate func() float64, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
 
+		requestID := RequestIDFromContext(r.Context())
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		requestLogger := logger.With(slog.String("request_id", requestID))
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			requestLogger = requestLogger.With(slog.String("trace_id", span.TraceID().String()))
+		}
+		ctx := logging.NewContext(r.Context(), requestLogger)
+		ctx, fields := logging.NewFieldsContext(ctx)
+
 		rw := &responseWriter{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
-		next.ServeHTTP(rw, r)
-
-		log.Printf(
-			"[%s] %s %s %s - %d %s",
-			r.RemoteAddr,
-			r.Method,
-			r.RequestURI,
-			r.Proto,
-			rw.statusCode,
-			time.Since(startTime),
-		)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		switch {
+		case rw.statusCode >= 500:
+			level = slog.LevelError
+		case rw.statusCode >= 400:
+			level = slog.LevelWarn
+		case fields.Sampled() && rand.Float64() >= sampleRate():
+			return
+		}
+
+		attrs := append([]slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("uri", r.RequestURI),
+			slog.String("proto", r.Proto),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.Int("status", rw.statusCode),
+			slog.Duration("duration", time.Since(startTime)),
+		}, fields.Attrs()...)
+		requestLogger.LogAttrs(ctx, level, "Request served", attrs...)
 	})
 }
 
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"targeting-engine/internal/logging"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+	var requestLogger *slog.Logger
+	handler := LoggingMiddleware(logger, func() float64 { return 1 }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestLogger = logging.FromContext(r.Context())
+		logging.AddFields(r.Context(), slog.String("app", "spotify"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/delivery?app=spotify", nil))
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON log line but got %q", out.String())
+	}
+	if record["status"] != float64(204) || record["app"] != "spotify" || record["uri"] != "/v1/delivery?app=spotify" {
+		t.Errorf("Unexpected log record %v", record)
+	}
+	if id, _ := record["request_id"].(string); len(id) != 32 {
+		t.Errorf("Expected a generated request ID but got %v", record["request_id"])
+	}
+
+	out.Reset()
+	requestLogger.Info("from the handler")
+	if !strings.Contains(out.String(), record["request_id"].(string)) {
+		t.Errorf("Expected handler logs to carry the request ID, got %q", out.String())
+	}
+}
+
+func TestLoggingMiddlewareSampling(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+	status := http.StatusOK
+	handler := LoggingMiddleware(logger, func() float64 { return 0 }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.Sample(r.Context())
+		w.WriteHeader(status)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/delivery", nil))
+	if out.Len() != 0 {
+		t.Errorf("Expected successful sampled requests to be dropped, got %q", out.String())
+	}
+
+	status = http.StatusBadRequest
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/delivery", nil))
+	if !strings.Contains(out.String(), `"level":"WARN"`) {
+		t.Errorf("Expected failed requests to always be logged, got %q", out.String())
+	}
+}
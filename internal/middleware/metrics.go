@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"targeting-engine/internal/metrics"
+)
+
+// MetricsMiddleware serves requests with mux and records their count and
+// latency, labelled with the mux pattern that matched them.
+func MetricsMiddleware(m *metrics.Metrics, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+
+		rw := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		mux.ServeHTTP(rw, r)
+
+		m.ObserveRequest(route, r.Method, rw.statusCode, time.Since(startTime))
+	})
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "kept", incoming: "req-123", keep: true},
+		{name: "missing", incoming: ""},
+		{name: "too long", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "control characters", incoming: "req\n123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/delivery", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(RequestIDHeader)
+			if echoed == "" || echoed != seen {
+				t.Fatalf("Expected the handler's ID %q to be echoed but got %q", seen, echoed)
+			}
+			if tt.keep && echoed != tt.incoming {
+				t.Errorf("Expected the incoming ID to be kept but got %q", echoed)
+			}
+			if !tt.keep && len(echoed) != 32 {
+				t.Errorf("Expected a generated ID but got %q", echoed)
+			}
+		})
+	}
+}
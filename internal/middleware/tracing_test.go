@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	routes := http.NewServeMux()
+	routes.HandleFunc("/v1/delivery", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("Expected the handler to run inside a span")
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	handler := RequestIDMiddleware(TracingMiddleware(routes, routes))
+
+	req := httptest.NewRequest("GET", "/v1/delivery?app=spotify", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one span but got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /v1/delivery" {
+		t.Errorf("Expected the span to be named after the route but got %q", span.Name)
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the caller's trace to be continued but got %s", span.SpanContext.TraceID())
+	}
+	if span.Status.Code.String() != "Error" {
+		t.Errorf("Expected a 503 to mark the span as failed but got %v", span.Status)
+	}
+}
@@ -1,10 +1,27 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Status string
 
 const (
 	StatusActive   Status = "ACTIVE"
 	StatusInactive Status = "INACTIVE"
+	// StatusExhausted is set automatically once a lifetime budget is used up.
+	StatusExhausted Status = "EXHAUSTED"
+)
+
+// Pacing decides how a campaign's daily budgets are spent over the day.
+type Pacing string
+
+const (
+	// PacingASAP delivers as fast as requests allow until the budget runs out.
+	PacingASAP Pacing = "ASAP"
+	// PacingEven spreads the daily budgets evenly over the day.
+	PacingEven Pacing = "EVEN"
 )
 
 type Campaign struct {
@@ -13,12 +30,71 @@ type Campaign struct {
 	ImageURL string `json:"image_url"`
 	CTA      string `json:"cta"`
 	Status   Status `json:"status"`
+
+	// Priority ranks the campaign in delivery responses; higher comes first.
+	Priority int `json:"priority,omitempty"`
+	// Weight is the campaign's relative share among campaigns of the same
+	// priority when they are ranked at random. Zero counts as 1.
+	Weight int `json:"weight,omitempty"`
+	// CreativeRotation picks among the campaign's creatives, EVEN by
+	// default.
+	CreativeRotation Rotation `json:"creative_rotation,omitempty"`
+
+	// StartAt and EndAt bound the campaign's flight; either may be open.
+	StartAt *time.Time `json:"start_at,omitempty"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+	// TimeZone is the IANA zone dayparts are read in, UTC by default.
+	TimeZone string   `json:"time_zone,omitempty"`
+	Dayparts Dayparts `json:"dayparts,omitempty"`
+
+	// FrequencyCaps limit how often one device is shown the campaign.
+	FrequencyCaps FrequencyCaps `json:"frequency_caps,omitempty"`
+
+	// BidCPM is what the campaign pays per thousand impressions.
+	BidCPM float64 `json:"bid_cpm,omitempty"`
+	// Budgets cap delivered impressions and spend per day, in the campaign's
+	// time zone, and over the campaign's lifetime. Zero means no limit.
+	DailyImpressionBudget    int64   `json:"daily_impression_budget,omitempty"`
+	LifetimeImpressionBudget int64   `json:"lifetime_impression_budget,omitempty"`
+	DailySpendBudget         float64 `json:"daily_spend_budget,omitempty"`
+	LifetimeSpendBudget      float64 `json:"lifetime_spend_budget,omitempty"`
+	Pacing                   Pacing  `json:"pacing,omitempty"`
+
+	// Experiment, if any, splits the campaign's traffic between variants.
+	Experiment *Experiment `json:"experiment,omitempty"`
+}
+
+// RankWeight returns the campaign's weight, 1 when it has none.
+func (c *Campaign) RankWeight() int {
+	if c.Weight == 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// HasBudget reports whether any budget limits the campaign.
+func (c *Campaign) HasBudget() bool {
+	return c.DailyImpressionBudget > 0 || c.LifetimeImpressionBudget > 0 ||
+		c.DailySpendBudget > 0 || c.LifetimeSpendBudget > 0
 }
 
 type CampaignResponse struct {
 	CID string `json:"cid"`
 	Img string `json:"img"`
 	CTA string `json:"cta"`
+	// The creative fields are set when the campaign has creatives. Img is
+	// then the creative's URL.
+	CreativeID string       `json:"crid,omitempty"`
+	Type       CreativeType `json:"type,omitempty"`
+	HTML       string       `json:"html,omitempty"`
+	Width      int          `json:"width,omitempty"`
+	Height     int          `json:"height,omitempty"`
+	// Price is the CPM the campaign pays, set when it won an auction.
+	Price *float64 `json:"price,omitempty"`
+	// Experiment and Arm are set when the device is in one of the
+	// campaign's experiment arms.
+	Experiment string `json:"experiment,omitempty"`
+	Arm        string `json:"arm,omitempty"`
 }
 
 func (c *Campaign) ToCampaignResponse() CampaignResponse {
@@ -28,3 +104,106 @@ func (c *Campaign) ToCampaignResponse() CampaignResponse {
 		CTA: c.CTA,
 	}
 }
+
+// Nullable is a patch field that can be cleared: it is Set when the field
+// is present, with a nil Value when the field is null.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set, n.Value = true, nil
+	if string(data) == "null" {
+		return nil
+	}
+	n.Value = new(T)
+	return json.Unmarshal(data, n.Value)
+}
+
+// CampaignPatch holds the campaign fields a partial update changes; nil
+// fields are left as they are. Nullable fields are cleared by null.
+type CampaignPatch struct {
+	Name             *string             `json:"name"`
+	ImageURL         *string             `json:"image_url"`
+	CTA              *string             `json:"cta"`
+	Status           *Status             `json:"status"`
+	Priority         *int                `json:"priority"`
+	Weight           *int                `json:"weight"`
+	CreativeRotation *Rotation           `json:"creative_rotation"`
+	StartAt          Nullable[time.Time] `json:"start_at"`
+	EndAt            Nullable[time.Time] `json:"end_at"`
+	TimeZone         *string             `json:"time_zone"`
+	Dayparts         *Dayparts           `json:"dayparts"`
+
+	FrequencyCaps *FrequencyCaps `json:"frequency_caps"`
+
+	BidCPM                   *float64 `json:"bid_cpm"`
+	DailyImpressionBudget    *int64   `json:"daily_impression_budget"`
+	LifetimeImpressionBudget *int64   `json:"lifetime_impression_budget"`
+	DailySpendBudget         *float64 `json:"daily_spend_budget"`
+	LifetimeSpendBudget      *float64 `json:"lifetime_spend_budget"`
+	Pacing                   *Pacing  `json:"pacing"`
+
+	Experiment Nullable[Experiment] `json:"experiment"`
+}
+
+func (p CampaignPatch) Apply(c *Campaign) {
+	if p.Name != nil {
+		c.Name = *p.Name
+	}
+	if p.ImageURL != nil {
+		c.ImageURL = *p.ImageURL
+	}
+	if p.CTA != nil {
+		c.CTA = *p.CTA
+	}
+	if p.Status != nil {
+		c.Status = *p.Status
+	}
+	if p.Priority != nil {
+		c.Priority = *p.Priority
+	}
+	if p.Weight != nil {
+		c.Weight = *p.Weight
+	}
+	if p.CreativeRotation != nil {
+		c.CreativeRotation = *p.CreativeRotation
+	}
+	if p.StartAt.Set {
+		c.StartAt = p.StartAt.Value
+	}
+	if p.EndAt.Set {
+		c.EndAt = p.EndAt.Value
+	}
+	if p.TimeZone != nil {
+		c.TimeZone = *p.TimeZone
+	}
+	if p.Dayparts != nil {
+		c.Dayparts = *p.Dayparts
+	}
+	if p.FrequencyCaps != nil {
+		c.FrequencyCaps = *p.FrequencyCaps
+	}
+	if p.BidCPM != nil {
+		c.BidCPM = *p.BidCPM
+	}
+	if p.DailyImpressionBudget != nil {
+		c.DailyImpressionBudget = *p.DailyImpressionBudget
+	}
+	if p.LifetimeImpressionBudget != nil {
+		c.LifetimeImpressionBudget = *p.LifetimeImpressionBudget
+	}
+	if p.DailySpendBudget != nil {
+		c.DailySpendBudget = *p.DailySpendBudget
+	}
+	if p.LifetimeSpendBudget != nil {
+		c.LifetimeSpendBudget = *p.LifetimeSpendBudget
+	}
+	if p.Pacing != nil {
+		c.Pacing = *p.Pacing
+	}
+	if p.Experiment.Set {
+		c.Experiment = p.Experiment.Value
+	}
+}
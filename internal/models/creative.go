@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CreativeType is the kind of asset a creative shows.
+type CreativeType string
+
+const (
+	CreativeImage CreativeType = "IMAGE"
+	CreativeVideo CreativeType = "VIDEO"
+	CreativeHTML  CreativeType = "HTML"
+)
+
+// Rotation decides which of a campaign's creatives a delivery shows.
+type Rotation string
+
+const (
+	// RotationEven shows every creative equally often, at random.
+	RotationEven Rotation = "EVEN"
+	// RotationWeighted shows creatives in proportion to their weights.
+	RotationWeighted Rotation = "WEIGHTED"
+	// RotationCTR shows the creative with the best click-through rate, while
+	// still trying the others.
+	RotationCTR Rotation = "CTR"
+)
+
+// Creative is one of the ads a campaign can show. A campaign without
+// creatives shows its own image and CTA.
+type Creative struct {
+	CampaignID string       `json:"campaign_id"`
+	ID         string       `json:"id"`
+	Type       CreativeType `json:"type"`
+	// URL is the image or video; HTML creatives carry their markup in HTML.
+	URL  string `json:"url,omitempty"`
+	HTML string `json:"html,omitempty"`
+	// Width and Height, in pixels, limit the creative to requests for that
+	// size or for no size in particular.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// CTA replaces the campaign's call to action.
+	CTA string `json:"cta,omitempty"`
+	// Locale, like "en" or "pt-BR", limits the creative to requests in that
+	// language or in no language in particular.
+	Locale string `json:"locale,omitempty"`
+	// Weight is the creative's share under WEIGHTED rotation. Zero counts
+	// as 1.
+	Weight int `json:"weight,omitempty"`
+}
+
+// RotationWeight returns the creative's weight, 1 when it has none.
+func (c *Creative) RotationWeight() int {
+	if c.Weight == 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// Matches reports whether the creative may be shown for a request of size
+// (such as "300x250") and language, either of which may be empty.
+func (c *Creative) Matches(size, language string) bool {
+	if size != "" && c.Width > 0 {
+		width, height, _ := ParseSize(size)
+		if width != c.Width || height != c.Height {
+			return false
+		}
+	}
+	if language != "" && c.Locale != "" {
+		language, locale := strings.ToLower(language), strings.ToLower(c.Locale)
+		if language != locale && !strings.HasPrefix(language, locale+"-") {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseSize parses a size written as WIDTHxHEIGHT, such as "320x50".
+func ParseSize(size string) (width, height int, err error) {
+	w, h, ok := strings.Cut(strings.ToLower(size), "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+		if err == nil {
+			height, err = strconv.Atoi(h)
+		}
+	}
+	if !ok || err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid size %q, must be WIDTHxHEIGHT", size)
+	}
+	return width, height, nil
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Experiment splits a campaign's traffic between arms that target it or show
+// it differently, so variants can be compared without duplicating the
+// campaign. Devices are assigned to arms by a hash of their ID, so each keeps
+// seeing the same arm.
+type Experiment struct {
+	ID   string `json:"id"`
+	Arms []Arm  `json:"arms"`
+}
+
+// Arm is one variant of an experiment.
+type Arm struct {
+	ID string `json:"id"`
+	// Traffic is the percentage of devices assigned to the arm. Devices
+	// left over by the arms, and requests without a device ID, aren't in
+	// the experiment and see the campaign as it is.
+	Traffic float64 `json:"traffic"`
+	// Rules replace the campaign's rules for the same dimensions. Their
+	// campaign_id may be left out.
+	Rules []TargetingRule `json:"rules,omitempty"`
+	// Creatives, by ID, limit the arm to some of the campaign's creatives.
+	Creatives []string `json:"creatives,omitempty"`
+}
+
+// armBuckets is how finely traffic is split: percentages are honoured to two
+// decimals.
+const armBuckets = 10000
+
+// Assign returns the arm of the device, or nil when it is in none.
+func (e *Experiment) Assign(deviceID string) *Arm {
+	if e == nil || deviceID == "" {
+		return nil
+	}
+
+	// Hashing the experiment ID too keeps the arms of different experiments
+	// independent.
+	h := fnv.New64a()
+	h.Write([]byte(e.ID))
+	h.Write([]byte{0})
+	h.Write([]byte(deviceID))
+	bucket := float64(h.Sum64() % armBuckets)
+
+	var upper float64
+	for i := range e.Arms {
+		upper += e.Arms[i].Traffic * armBuckets / 100
+		if bucket < upper {
+			return &e.Arms[i]
+		}
+	}
+	return nil
+}
+
+// HasCreative reports whether the arm may show the creative.
+func (a *Arm) HasCreative(id string) bool {
+	if len(a.Creatives) == 0 {
+		return true
+	}
+	for _, creative := range a.Creatives {
+		if creative == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Campaign) validateExperiment() error {
+	e := c.Experiment
+	if e == nil {
+		return nil
+	}
+	if strings.TrimSpace(e.ID) == "" {
+		return &ValidationError{Field: "experiment.id", Message: "must not be empty"}
+	}
+	if len(e.Arms) == 0 {
+		return &ValidationError{Field: "experiment.arms", Message: "must not be empty"}
+	}
+
+	var traffic float64
+	arms := make(map[string]bool, len(e.Arms))
+	for i, arm := range e.Arms {
+		field := fmt.Sprintf("experiment.arms[%d]", i)
+		if strings.TrimSpace(arm.ID) == "" {
+			return &ValidationError{Field: field + ".id", Message: "must not be empty"}
+		}
+		if arms[arm.ID] {
+			return &ValidationError{Field: field + ".id", Message: fmt.Sprintf("arm %s is listed twice", arm.ID)}
+		}
+		arms[arm.ID] = true
+		if arm.Traffic <= 0 {
+			return &ValidationError{Field: field + ".traffic", Message: "must be positive"}
+		}
+		traffic += arm.Traffic
+
+		dimensions := make(map[DimensionType]bool, len(arm.Rules))
+		for j, rule := range arm.Rules {
+			if rule.CampaignID == "" {
+				rule.CampaignID = c.ID
+			}
+			ruleField := fmt.Sprintf("%s.rules[%d]", field, j)
+			if rule.CampaignID != c.ID {
+				return &ValidationError{Field: ruleField + ".campaign_id", Message: "must be the campaign's"}
+			}
+			if err := rule.Validate(); err != nil {
+				var validationErr *ValidationError
+				if errors.As(err, &validationErr) {
+					return &ValidationError{Field: ruleField + "." + validationErr.Field, Message: validationErr.Message}
+				}
+				return err
+			}
+			if dimensions[rule.DimensionType] {
+				return &ValidationError{Field: ruleField + ".dimension_type", Message: fmt.Sprintf("the arm has two %s rules", rule.DimensionType)}
+			}
+			dimensions[rule.DimensionType] = true
+		}
+		for j, creative := range arm.Creatives {
+			if strings.TrimSpace(creative) == "" {
+				return &ValidationError{Field: fmt.Sprintf("%s.creatives[%d]", field, j), Message: "must not be empty"}
+			}
+		}
+	}
+	if traffic > 100 {
+		return &ValidationError{Field: "experiment.arms", Message: fmt.Sprintf("traffic adds up to %v%%, more than 100%%", traffic)}
+	}
+	return nil
+}
+
+// Value stores an experiment as JSON, and no experiment as NULL.
+func (e *Experiment) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(*e)
+}
+
+// Scan reads an experiment stored as JSON.
+func (e *Experiment) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return errors.New("experiments must be stored as JSON")
+	}
+}
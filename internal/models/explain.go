@@ -0,0 +1,50 @@
+package models
+
+// Check names, in the order a delivery applies them.
+const (
+	CheckStatus       = "status"
+	CheckLoad         = "load"
+	CheckSchedule     = "schedule"
+	CheckRule         = "rule"
+	CheckExpression   = "expression"
+	CheckCreatives    = "creatives"
+	CheckFrequencyCap = "frequency_cap"
+	CheckBudget       = "budget"
+	CheckAuction      = "auction"
+)
+
+// Explanation tells, for every campaign, whether a delivery request could
+// return it and why.
+type Explanation struct {
+	Request   DeliveryRequest       `json:"request"`
+	Campaigns []CampaignExplanation `json:"campaigns"`
+}
+
+// CampaignExplanation is the outcome of every check of one campaign against
+// a request.
+type CampaignExplanation struct {
+	CID    string `json:"cid"`
+	Status Status `json:"status"`
+	// Eligible is set when every check passed. Eligible campaigns are
+	// delivered unless the request's limit cuts them off.
+	Eligible bool `json:"eligible"`
+	// Reason is why the first failing check failed.
+	Reason     string  `json:"reason,omitempty"`
+	Experiment string  `json:"experiment,omitempty"`
+	Arm        string  `json:"arm,omitempty"`
+	Checks     []Check `json:"checks"`
+}
+
+// Check is the outcome of one check, such as a targeting rule.
+type Check struct {
+	Check string `json:"check"`
+	// The rule fields are set for rule checks. RequestValue is the request's
+	// value for the rule's dimension.
+	Dimension    DimensionType `json:"dimension,omitempty"`
+	RuleType     RuleType      `json:"rule_type,omitempty"`
+	MatchType    MatchType     `json:"match_type,omitempty"`
+	Values       []string      `json:"values,omitempty"`
+	RequestValue *string       `json:"request_value,omitempty"`
+	Pass         bool          `json:"pass"`
+	Reason       string        `json:"reason"`
+}
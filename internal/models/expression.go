@@ -0,0 +1,129 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxExpressionDepth bounds how deeply expressions may nest.
+const maxExpressionDepth = 16
+
+// Expression is a boolean targeting expression. Exactly one of And, Or, Not
+// or Dimension is set; a node with Dimension set is a predicate that holds
+// when the request's value for that dimension matches one of Values using
+// Match, or for version dimensions satisfies one of the version ranges in Values.
+//
+//	{"or": [
+//	  {"and": [{"dimension": "COUNTRY", "values": ["US", "CA"]}, {"dimension": "OS", "values": ["iOS"]}]},
+//	  {"dimension": "APP", "values": ["x", "y"]}
+//	]}
+type Expression struct {
+	And []Expression `json:"and,omitempty"`
+	Or  []Expression `json:"or,omitempty"`
+	Not *Expression  `json:"not,omitempty"`
+
+	Dimension DimensionType `json:"dimension,omitempty"`
+	Match     MatchType     `json:"match,omitempty"`
+	Values    []string      `json:"values,omitempty"`
+}
+
+// TargetingExpression attaches an expression to a campaign. It is ANDed with
+// the campaign's flat targeting rules.
+type TargetingExpression struct {
+	CampaignID string     `json:"campaign_id"`
+	Expression Expression `json:"expression"`
+}
+
+// RulesExpression returns the expression equivalent to a campaign's flat
+// rules: every INCLUDE rule must hold and no EXCLUDE rule may.
+func RulesExpression(rules []TargetingRule) Expression {
+	expr := Expression{And: []Expression{}}
+	for _, rule := range rules {
+		predicate := Expression{Dimension: rule.DimensionType, Match: rule.MatchType, Values: rule.Values}
+		if rule.RuleType == Exclude {
+			expr.And = append(expr.And, Expression{Not: &predicate})
+			continue
+		}
+		expr.And = append(expr.And, predicate)
+	}
+	return expr
+}
+
+func (e *Expression) Validate() error {
+	return e.validate("expression", 0)
+}
+
+func (e *Expression) validate(path string, depth int) error {
+	if depth > maxExpressionDepth {
+		return &ValidationError{Field: path, Message: fmt.Sprintf("nested more than %d levels deep", maxExpressionDepth)}
+	}
+
+	set := 0
+	for _, ok := range []bool{e.And != nil, e.Or != nil, e.Not != nil, e.Dimension != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return &ValidationError{Field: path, Message: "must have exactly one of and, or, not or dimension"}
+	}
+	if e.Dimension == "" && (e.Match != "" || e.Values != nil) {
+		return &ValidationError{Field: path, Message: "match and values need a dimension"}
+	}
+
+	switch {
+	case e.And != nil:
+		return validateChildren(path+".and", e.And, depth)
+	case e.Or != nil:
+		return validateChildren(path+".or", e.Or, depth)
+	case e.Not != nil:
+		return e.Not.validate(path+".not", depth+1)
+	}
+
+	if !e.Dimension.Valid() {
+		return &ValidationError{Field: path + ".dimension", Message: "unknown dimension " + string(e.Dimension)}
+	}
+	if err := validateMatchType(path+".match", e.Dimension, e.Match); err != nil {
+		return err
+	}
+	return validateValues(path+".values", e.Dimension, e.Match, e.Values)
+}
+
+func validateChildren(path string, children []Expression, depth int) error {
+	if len(children) == 0 {
+		return &ValidationError{Field: path, Message: "must not be empty"}
+	}
+	for i := range children {
+		if err := children[i].validate(fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TargetingExpression) Validate() error {
+	if strings.TrimSpace(t.CampaignID) == "" {
+		return &ValidationError{Field: "campaign_id", Message: "must not be empty"}
+	}
+	return t.Expression.Validate()
+}
+
+// Value stores an expression as JSON.
+func (e Expression) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+// Scan reads an expression stored as JSON.
+func (e *Expression) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return errors.New("expression must be stored as JSON")
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CapPeriod is the rolling window a frequency cap counts exposures over.
+type CapPeriod string
+
+const (
+	CapPerHour CapPeriod = "HOUR"
+	CapPerDay  CapPeriod = "DAY"
+	CapPerWeek CapPeriod = "WEEK"
+)
+
+func (p CapPeriod) Duration() time.Duration {
+	switch p {
+	case CapPerHour:
+		return time.Hour
+	case CapPerDay:
+		return 24 * time.Hour
+	case CapPerWeek:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// FrequencyCap limits a device to Max exposures of a campaign within the
+// last Period, for example {"max": 3, "period": "DAY"}.
+type FrequencyCap struct {
+	Max    int       `json:"max"`
+	Period CapPeriod `json:"period"`
+}
+
+// FrequencyCaps is stored as JSON.
+type FrequencyCaps []FrequencyCap
+
+func (c *Campaign) validateFrequencyCaps() error {
+	for i, fc := range c.FrequencyCaps {
+		field := fmt.Sprintf("frequency_caps[%d]", i)
+		if fc.Max < 1 {
+			return &ValidationError{Field: field + ".max", Message: "must be at least 1"}
+		}
+		if fc.Period.Duration() == 0 {
+			return &ValidationError{Field: field + ".period", Message: fmt.Sprintf("must be %s, %s or %s", CapPerHour, CapPerDay, CapPerWeek)}
+		}
+	}
+	return nil
+}
+
+// Value stores frequency caps as JSON.
+func (c FrequencyCaps) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]FrequencyCap(c))
+}
+
+// Scan reads frequency caps stored as JSON.
+func (c *FrequencyCaps) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, (*[]FrequencyCap)(c))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]FrequencyCap)(c))
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return errors.New("frequency caps must be stored as JSON")
+	}
+}
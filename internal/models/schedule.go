@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Daypart is a weekly delivery window in the campaign's time zone, for
+// example {"days": ["MON", "FRI"], "start": "09:00", "end": "17:30"}. Start is
+// inclusive and end exclusive; "24:00" ends the window at midnight. Windows
+// can't wrap past midnight, so overnight delivery needs two of them. No
+// days means every day.
+type Daypart struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// Dayparts is stored as JSON. A campaign without dayparts runs all day.
+type Dayparts []Daypart
+
+var weekdays = map[string]time.Weekday{
+	"SUN": time.Sunday,
+	"MON": time.Monday,
+	"TUE": time.Tuesday,
+	"WED": time.Wednesday,
+	"THU": time.Thursday,
+	"FRI": time.Friday,
+	"SAT": time.Saturday,
+}
+
+// ParseWeekday reads a three-letter day name such as "MON", ignoring case.
+func ParseWeekday(day string) (time.Weekday, error) {
+	weekday, ok := weekdays[strings.ToUpper(day)]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q, use MON, TUE, WED, THU, FRI, SAT or SUN", day)
+	}
+	return weekday, nil
+}
+
+// ParseClock reads an "HH:MM" time of day and returns minutes after midnight.
+// "24:00" is accepted as the end of the day.
+func ParseClock(clock string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(clock, "%2d:%2d", &hour, &minute); err != nil || len(clock) != 5 {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", clock)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", clock)
+	}
+	return hour*60 + minute, nil
+}
+
+// Location returns the campaign's time zone, UTC when none is set.
+func (c *Campaign) Location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(c.TimeZone)
+}
+
+func (c *Campaign) validateSchedule() error {
+	if c.StartAt != nil && c.EndAt != nil && !c.EndAt.After(*c.StartAt) {
+		return &ValidationError{Field: "end_at", Message: "must be after start_at"}
+	}
+	if _, err := c.Location(); err != nil {
+		return &ValidationError{Field: "time_zone", Message: err.Error()}
+	}
+	for i, daypart := range c.Dayparts {
+		field := fmt.Sprintf("dayparts[%d]", i)
+		for _, day := range daypart.Days {
+			if _, err := ParseWeekday(day); err != nil {
+				return &ValidationError{Field: field + ".days", Message: err.Error()}
+			}
+		}
+		start, err := ParseClock(daypart.Start)
+		if err != nil {
+			return &ValidationError{Field: field + ".start", Message: err.Error()}
+		}
+		end, err := ParseClock(daypart.End)
+		if err != nil {
+			return &ValidationError{Field: field + ".end", Message: err.Error()}
+		}
+		if end <= start {
+			return &ValidationError{Field: field + ".end", Message: "must be after start"}
+		}
+	}
+	return nil
+}
+
+// Value stores dayparts as JSON.
+func (d Dayparts) Value() (driver.Value, error) {
+	if d == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Daypart(d))
+}
+
+// Scan reads dayparts stored as JSON.
+func (d *Dayparts) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, (*[]Daypart)(d))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]Daypart)(d))
+	case nil:
+		*d = nil
+		return nil
+	default:
+		return errors.New("dayparts must be stored as JSON")
+	}
+}
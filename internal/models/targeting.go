@@ -11,25 +11,74 @@ const (
 	Exclude RuleType = "EXCLUDE"
 )
 
+// MatchType selects how a rule's values are compared with request values.
+// Comparisons ignore case.
+type MatchType string
+
+const (
+	MatchExact  MatchType = "EXACT"
+	MatchPrefix MatchType = "PREFIX"
+	MatchSuffix MatchType = "SUFFIX"
+	// MatchGlob values may use * for any run of characters and ? for one.
+	MatchGlob MatchType = "GLOB"
+	// MatchRegex values are RE2 regular expressions matched anywhere in the
+	// value unless anchored with ^ and $.
+	MatchRegex MatchType = "REGEX"
+)
+
+// OrDefault returns m, or MatchExact when m is empty.
+func (m MatchType) OrDefault() MatchType {
+	if m == "" {
+		return MatchExact
+	}
+	return m
+}
+
 type DimensionType string
 
 const (
 	DimensionApp     DimensionType = "APP"
 	DimensionCountry DimensionType = "COUNTRY"
 	DimensionOS      DimensionType = "OS"
+
+	// Version dimensions hold range constraints such as ">=10" or
+	// "4.2.0 - 5.x" instead of plain values.
+	DimensionOSVersion  DimensionType = "OS_VERSION"
+	DimensionAppVersion DimensionType = "APP_VERSION"
 )
 
+// Dimensions lists every dimension rules can target.
+var Dimensions = []DimensionType{
+	DimensionApp,
+	DimensionCountry,
+	DimensionOS,
+	DimensionOSVersion,
+	DimensionAppVersion,
+}
+
 type TargetingRule struct {
 	CampaignID    string         `json:"campaign_id"`
 	DimensionType DimensionType  `json:"dimension_type"`
 	RuleType      RuleType       `json:"rule_type"`
+	MatchType     MatchType      `json:"match_type,omitempty"`
 	Values        pq.StringArray `json:"values"`
 }
 
 type DeliveryRequest struct {
-	App     string `json:"app"`
-	OS      string `json:"os"`
-	Country string `json:"country"`
+	App        string `json:"app"`
+	OS         string `json:"os"`
+	Country    string `json:"country"`
+	OSVersion  string `json:"os_version,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+	// DeviceID identifies the device or user for frequency capping.
+	DeviceID string `json:"device_id,omitempty"`
+	// Size, such as "300x250", and Language, such as "pt-BR", choose among
+	// a campaign's creatives.
+	Size     string `json:"size,omitempty"`
+	Language string `json:"lang,omitempty"`
+	// Limit caps the number of campaigns returned, best ranked first. Zero
+	// returns every match.
+	Limit int `json:"limit,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"targeting-engine/internal/version"
+)
+
+// ValidationError reports a field that failed validation.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+func (s Status) Valid() bool {
+	return s == StatusActive || s == StatusInactive || s == StatusExhausted
+}
+
+func (p Pacing) Valid() bool {
+	return p == "" || p == PacingASAP || p == PacingEven
+}
+
+func (t CreativeType) Valid() bool {
+	return t == CreativeImage || t == CreativeVideo || t == CreativeHTML
+}
+
+func (r Rotation) Valid() bool {
+	return r == "" || r == RotationEven || r == RotationWeighted || r == RotationCTR
+}
+
+func (t RuleType) Valid() bool {
+	return t == Include || t == Exclude
+}
+
+func (d DimensionType) Valid() bool {
+	for _, dim := range Dimensions {
+		if d == dim {
+			return true
+		}
+	}
+	return false
+}
+
+// IsVersion reports whether values for d are version range constraints.
+func (d DimensionType) IsVersion() bool {
+	return d == DimensionOSVersion || d == DimensionAppVersion
+}
+
+func (m MatchType) Valid() bool {
+	switch m.OrDefault() {
+	case MatchExact, MatchPrefix, MatchSuffix, MatchGlob, MatchRegex:
+		return true
+	default:
+		return false
+	}
+}
+
+func validateMatchType(field string, dimension DimensionType, match MatchType) error {
+	if !match.Valid() {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must be %s, %s, %s, %s or %s", MatchExact, MatchPrefix, MatchSuffix, MatchGlob, MatchRegex)}
+	}
+	if dimension.IsVersion() && match.OrDefault() != MatchExact {
+		return &ValidationError{Field: field, Message: "version dimensions only take version ranges"}
+	}
+	return nil
+}
+
+// validateValues checks the values a rule or predicate lists for dimension.
+func validateValues(field string, dimension DimensionType, match MatchType, values []string) error {
+	if len(values) == 0 {
+		return &ValidationError{Field: field, Message: "must not be empty"}
+	}
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			return &ValidationError{Field: field, Message: "must not contain empty values"}
+		}
+		if dimension.IsVersion() {
+			if _, err := version.ParseConstraint(value); err != nil {
+				return &ValidationError{Field: field, Message: err.Error()}
+			}
+		}
+		if match == MatchRegex {
+			if _, err := regexp.Compile(value); err != nil {
+				return &ValidationError{Field: field, Message: err.Error()}
+			}
+		}
+	}
+	return nil
+}
+
+func dimensionNames() string {
+	names := make([]string, len(Dimensions))
+	for i, dim := range Dimensions {
+		names[i] = string(dim)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c *Campaign) Validate() error {
+	if strings.TrimSpace(c.ID) == "" {
+		return &ValidationError{Field: "id", Message: "must not be empty"}
+	}
+	if strings.ContainsAny(c.ID, "/?#") {
+		return &ValidationError{Field: "id", Message: "must not contain '/', '?' or '#'"}
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		return &ValidationError{Field: "name", Message: "must not be empty"}
+	}
+	if strings.TrimSpace(c.ImageURL) == "" {
+		return &ValidationError{Field: "image_url", Message: "must not be empty"}
+	}
+	if strings.TrimSpace(c.CTA) == "" {
+		return &ValidationError{Field: "cta", Message: "must not be empty"}
+	}
+	if !c.Status.Valid() {
+		return &ValidationError{Field: "status", Message: fmt.Sprintf("must be %s, %s or %s", StatusActive, StatusInactive, StatusExhausted)}
+	}
+	if c.Weight < 0 {
+		return &ValidationError{Field: "weight", Message: "must not be negative"}
+	}
+	if !c.CreativeRotation.Valid() {
+		return &ValidationError{Field: "creative_rotation", Message: fmt.Sprintf("must be %s, %s or %s", RotationEven, RotationWeighted, RotationCTR)}
+	}
+	if err := c.validateSchedule(); err != nil {
+		return err
+	}
+	if err := c.validateFrequencyCaps(); err != nil {
+		return err
+	}
+	if err := c.validateExperiment(); err != nil {
+		return err
+	}
+	return c.validateBudget()
+}
+
+func (c *Campaign) validateBudget() error {
+	switch {
+	case c.BidCPM < 0:
+		return &ValidationError{Field: "bid_cpm", Message: "must not be negative"}
+	case c.DailyImpressionBudget < 0:
+		return &ValidationError{Field: "daily_impression_budget", Message: "must not be negative"}
+	case c.LifetimeImpressionBudget < 0:
+		return &ValidationError{Field: "lifetime_impression_budget", Message: "must not be negative"}
+	case c.DailySpendBudget < 0:
+		return &ValidationError{Field: "daily_spend_budget", Message: "must not be negative"}
+	case c.LifetimeSpendBudget < 0:
+		return &ValidationError{Field: "lifetime_spend_budget", Message: "must not be negative"}
+	case (c.DailySpendBudget > 0 || c.LifetimeSpendBudget > 0) && c.BidCPM == 0:
+		return &ValidationError{Field: "bid_cpm", Message: "must be set for spend budgets"}
+	case !c.Pacing.Valid():
+		return &ValidationError{Field: "pacing", Message: fmt.Sprintf("must be %s or %s", PacingASAP, PacingEven)}
+	}
+	return nil
+}
+
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+func (c *Creative) Validate() error {
+	if strings.TrimSpace(c.CampaignID) == "" {
+		return &ValidationError{Field: "campaign_id", Message: "must not be empty"}
+	}
+	if strings.TrimSpace(c.ID) == "" {
+		return &ValidationError{Field: "id", Message: "must not be empty"}
+	}
+	if strings.ContainsAny(c.ID, "/?#") {
+		return &ValidationError{Field: "id", Message: "must not contain '/', '?' or '#'"}
+	}
+	switch c.Type {
+	case CreativeImage, CreativeVideo:
+		if strings.TrimSpace(c.URL) == "" {
+			return &ValidationError{Field: "url", Message: "must be set for " + string(c.Type) + " creatives"}
+		}
+	case CreativeHTML:
+		if strings.TrimSpace(c.HTML) == "" {
+			return &ValidationError{Field: "html", Message: "must be set for HTML creatives"}
+		}
+	default:
+		return &ValidationError{Field: "type", Message: fmt.Sprintf("must be %s, %s or %s", CreativeImage, CreativeVideo, CreativeHTML)}
+	}
+	if c.Width < 0 || c.Height < 0 || (c.Width == 0) != (c.Height == 0) {
+		return &ValidationError{Field: "width", Message: "width and height must both be positive or both be unset"}
+	}
+	if c.Locale != "" && !localePattern.MatchString(c.Locale) {
+		return &ValidationError{Field: "locale", Message: "must be a language tag such as en or pt-BR"}
+	}
+	if c.Weight < 0 {
+		return &ValidationError{Field: "weight", Message: "must not be negative"}
+	}
+	return nil
+}
+
+func (r *TargetingRule) Validate() error {
+	if strings.TrimSpace(r.CampaignID) == "" {
+		return &ValidationError{Field: "campaign_id", Message: "must not be empty"}
+	}
+	if !r.DimensionType.Valid() {
+		return &ValidationError{Field: "dimension_type", Message: "must be one of " + dimensionNames()}
+	}
+	if !r.RuleType.Valid() {
+		return &ValidationError{Field: "rule_type", Message: fmt.Sprintf("must be %s or %s", Include, Exclude)}
+	}
+	if err := validateMatchType("match_type", r.DimensionType, r.MatchType); err != nil {
+		return err
+	}
+	return validateValues("values", r.DimensionType, r.MatchType, r.Values)
+}
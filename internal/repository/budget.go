@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"targeting-engine/internal/budget"
+
+	"github.com/lib/pq"
+)
+
+// PostgresBudgetStore keeps campaign usage counters in PostgreSQL, so budgets
+// hold across restarts and every instance of the service.
+type PostgresBudgetStore struct {
+	db *tracedDB
+}
+
+// BudgetStore returns a budget.Store sharing the repository's connection pool.
+func (r *PostgresRepository) BudgetStore() *PostgresBudgetStore {
+	return &PostgresBudgetStore{db: r.db}
+}
+
+var _ budget.Store = (*PostgresBudgetStore)(nil)
+
+func (s *PostgresBudgetStore) Usage(ctx context.Context, keys []budget.Key) (map[string]budget.Usage, error) {
+	return queryUsage(ctx, s.db, keys)
+}
+
+// queryer is implemented by both tracedDB and tracedTx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryUsage reads the usage for keys, inside a transaction or not.
+func queryUsage(ctx context.Context, q queryer, keys []budget.Key) (map[string]budget.Usage, error) {
+	ids := make([]string, len(keys))
+	days := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key.CampaignID
+		days[i] = key.Day
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT u.campaign_id,
+			COALESCE(SUM(u.impressions) FILTER (WHERE u.day = k.day), 0),
+			COALESCE(SUM(u.spend) FILTER (WHERE u.day = k.day), 0),
+			SUM(u.impressions),
+			SUM(u.spend)
+		FROM campaign_budget_usage u
+		JOIN unnest($1::text[], $2::date[]) AS k(campaign_id, day) ON k.campaign_id = u.campaign_id
+		GROUP BY u.campaign_id
+	`, pq.StringArray(ids), pq.StringArray(days))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[string]budget.Usage, len(keys))
+	for rows.Next() {
+		var id string
+		var u budget.Usage
+		if err := rows.Scan(&id, &u.DailyImpressions, &u.DailySpend, &u.LifetimeImpressions, &u.LifetimeSpend); err != nil {
+			return nil, err
+		}
+		usage[id] = u
+	}
+
+	return usage, rows.Err()
+}
+
+func (s *PostgresBudgetStore) Add(ctx context.Context, key budget.Key, impressions int64, spend float64) (budget.Usage, error) {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO campaign_budget_usage (campaign_id, day, impressions, spend)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (campaign_id, day) DO UPDATE
+		SET impressions = campaign_budget_usage.impressions + EXCLUDED.impressions,
+			spend = campaign_budget_usage.spend + EXCLUDED.spend
+	`, key.CampaignID, key.Day, impressions, spend)
+	if err != nil {
+		return budget.Usage{}, err
+	}
+
+	usage, err := s.Usage(ctx, []budget.Key{key})
+	return usage[key.CampaignID], err
+}
+
+func (s *PostgresBudgetStore) Reserve(ctx context.Context, key budget.Key, impressions int64, spend float64, allow func(budget.Usage) bool) (budget.Usage, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return budget.Usage{}, false, err
+	}
+	defer tx.Rollback()
+
+	// Concurrent reservations for the campaign wait here, so each one sees
+	// what the previous one added.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('budget:' || $1))`, key.CampaignID); err != nil {
+		return budget.Usage{}, false, err
+	}
+	usage, err := queryUsage(ctx, tx, []budget.Key{key})
+	if err != nil {
+		return budget.Usage{}, false, err
+	}
+	current := usage[key.CampaignID]
+	if !allow(current) {
+		return current, false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO campaign_budget_usage (campaign_id, day, impressions, spend)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (campaign_id, day) DO UPDATE
+		SET impressions = campaign_budget_usage.impressions + EXCLUDED.impressions,
+			spend = campaign_budget_usage.spend + EXCLUDED.spend
+	`, key.CampaignID, key.Day, impressions, spend)
+	if err != nil {
+		return budget.Usage{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return budget.Usage{}, false, err
+	}
+
+	current.DailyImpressions += impressions
+	current.DailySpend += spend
+	current.LifetimeImpressions += impressions
+	current.LifetimeSpend += spend
+	return current, true, nil
+}
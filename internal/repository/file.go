@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"targeting-engine/internal/fixtures"
+	"targeting-engine/internal/models"
+)
+
+// FileRepository serves the campaigns, rules and expressions declared in a
+// JSON or YAML file, in the fixtures format of the seed command. It is
+// read-only: writes fail with ErrReadOnly. A changed file is validated in
+// full before it replaces the current contents, so an invalid file leaves
+// the last good version in place.
+type FileRepository struct {
+	path string
+	mem  *MemoryRepository
+
+	// mu serializes reloads; reads go straight to mem.
+	mu   sync.Mutex
+	last os.FileInfo
+}
+
+var (
+	_ Repository = (*FileRepository)(nil)
+	_ Notifier   = (*FileRepository)(nil)
+)
+
+// NewFileRepository loads the campaigns file at path, which must be valid.
+func NewFileRepository(path string) (*FileRepository, error) {
+	r := &FileRepository{path: path, mem: NewMemoryRepository()}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads and validates the campaigns file and swaps it in. Listeners
+// get a change event for every campaign that differs. On error the current
+// contents are kept.
+func (r *FileRepository) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	f, err := fixtures.Load(r.path)
+	if err != nil {
+		return err
+	}
+
+	next := NewMemoryRepository()
+	if err := next.ApplyBatch(context.Background(), FixturesBatch(f)); err != nil {
+		return err
+	}
+	r.mem.replace(next)
+	r.last = info
+	return nil
+}
+
+// WatchFile reloads the campaigns file whenever it changes, checking every
+// interval until ctx is done. report is called with the outcome of each
+// reload.
+func (r *FileRepository) WatchFile(ctx context.Context, interval time.Duration, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(r.path)
+			r.mu.Lock()
+			last := r.last
+			r.mu.Unlock()
+			if err != nil || (info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+				continue
+			}
+			err = r.Reload()
+			if err != nil {
+				// Don't retry the same broken file on every tick.
+				r.mu.Lock()
+				r.last = info
+				r.mu.Unlock()
+			}
+			report(err)
+		}
+	}
+}
+
+func (r *FileRepository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	return r.mem.GetCampaigns(ctx)
+}
+
+func (r *FileRepository) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
+	return r.mem.GetCampaignByID(ctx, id)
+}
+
+func (r *FileRepository) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]models.Campaign, error) {
+	return r.mem.ListCampaigns(ctx, filter)
+}
+
+func (r *FileRepository) CreateCampaign(ctx context.Context, campaign models.Campaign) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) UpdateCampaign(ctx context.Context, campaign models.Campaign) error {
+	return ErrReadOnly
+}
+
+// UpdateCampaignStatus fails with ErrReadOnly, so campaigns that exhaust
+// their lifetime budget stay ACTIVE in the file and are filtered at delivery.
+func (r *FileRepository) UpdateCampaignStatus(ctx context.Context, id string, status models.Status) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) DeleteCampaign(ctx context.Context, id string) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) GetTargetingRules(ctx context.Context) ([]models.TargetingRule, error) {
+	return r.mem.GetTargetingRules(ctx)
+}
+
+func (r *FileRepository) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]models.TargetingRule, error) {
+	return r.mem.GetTargetingRulesByCampaignID(ctx, campaignID)
+}
+
+func (r *FileRepository) CreateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) UpdateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) DeleteTargetingRule(ctx context.Context, campaignID string, dimension models.DimensionType) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) GetTargetingExpressions(ctx context.Context) ([]models.TargetingExpression, error) {
+	return r.mem.GetTargetingExpressions(ctx)
+}
+
+func (r *FileRepository) GetTargetingExpressionByCampaignID(ctx context.Context, campaignID string) (*models.TargetingExpression, error) {
+	return r.mem.GetTargetingExpressionByCampaignID(ctx, campaignID)
+}
+
+func (r *FileRepository) SaveTargetingExpression(ctx context.Context, expr models.TargetingExpression) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) DeleteTargetingExpression(ctx context.Context, campaignID string) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) GetCreatives(ctx context.Context) ([]models.Creative, error) {
+	return r.mem.GetCreatives(ctx)
+}
+
+func (r *FileRepository) GetCreativesByCampaignID(ctx context.Context, campaignID string) ([]models.Creative, error) {
+	return r.mem.GetCreativesByCampaignID(ctx, campaignID)
+}
+
+func (r *FileRepository) CreateCreative(ctx context.Context, creative models.Creative) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) UpdateCreative(ctx context.Context, creative models.Creative) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) DeleteCreative(ctx context.Context, campaignID, id string) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) ApplyBatch(ctx context.Context, batch Batch) error {
+	return ErrReadOnly
+}
+
+func (r *FileRepository) Listen(ctx context.Context) (<-chan ChangeEvent, error) {
+	return r.mem.Listen(ctx)
+}
+
+func (r *FileRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *FileRepository) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+const campaignsFile = `
+campaigns:
+  - {id: spotify, name: Spotify, image_url: https://a, cta: Download, status: ACTIVE}
+  - {id: duolingo, name: Duolingo, image_url: https://b, cta: Install, status: ACTIVE}
+rules:
+  - {campaign_id: spotify, dimension_type: COUNTRY, rule_type: INCLUDE, values: [US]}
+`
+
+func TestFileRepository(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "campaigns.yaml")
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("campaigns: [{id: broken}]")
+	if _, err := NewFileRepository(path); err == nil {
+		t.Fatal("Expected an invalid file to be rejected on start")
+	}
+
+	write(campaignsFile)
+	repo, err := NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository failed: %v", err)
+	}
+	if campaigns, _ := repo.GetCampaigns(ctx); len(campaigns) != 2 {
+		t.Fatalf("Expected 2 campaigns but got %+v", campaigns)
+	}
+	if err := repo.DeleteCampaign(ctx, "spotify"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly but got %v", err)
+	}
+
+	events, _ := repo.Listen(ctx)
+	<-events // the initial resync
+
+	// An invalid file keeps the last good version.
+	write(campaignsFile + "  - {campaign_id: nobody, dimension_type: OS, rule_type: INCLUDE, values: [iOS]}\n")
+	if err := repo.Reload(); err == nil {
+		t.Fatal("Expected a rule of an unknown campaign to be rejected")
+	}
+	if rules, _ := repo.GetTargetingRules(ctx); len(rules) != 1 {
+		t.Errorf("Expected the last good rules but got %+v", rules)
+	}
+
+	// Only the campaign that changed is reported.
+	write(campaignsFile + "  - {campaign_id: duolingo, dimension_type: OS, rule_type: INCLUDE, values: [iOS]}\n")
+	if err := repo.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Kind != ChangeRow || ev.CampaignID != "duolingo" {
+			t.Errorf("Expected a change of duolingo but got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a change event")
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("Expected a single change event but also got %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	rules, _ := repo.GetTargetingRulesByCampaignID(ctx, "duolingo")
+	if len(rules) != 1 || rules[0].DimensionType != models.DimensionOS {
+		t.Errorf("Expected the new duolingo rule but got %+v", rules)
+	}
+}
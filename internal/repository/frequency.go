@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"targeting-engine/internal/frequency"
+
+	"github.com/lib/pq"
+)
+
+// PostgresFrequencyStore keeps frequency cap exposures in PostgreSQL so caps
+// hold across every instance of the service.
+type PostgresFrequencyStore struct {
+	db *tracedDB
+}
+
+// FrequencyStore returns a frequency.Store sharing the repository's connection pool.
+func (r *PostgresRepository) FrequencyStore() *PostgresFrequencyStore {
+	return &PostgresFrequencyStore{db: r.db}
+}
+
+var _ frequency.Store = (*PostgresFrequencyStore)(nil)
+
+func (s *PostgresFrequencyStore) Exposures(ctx context.Context, deviceID string, campaignIDs []string, since time.Time) (map[string][]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT campaign_id, shown_at
+		FROM frequency_exposures
+		WHERE device_id = $1 AND campaign_id = ANY($2) AND shown_at >= $3
+		ORDER BY shown_at
+	`, deviceID, pq.StringArray(campaignIDs), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exposures := make(map[string][]time.Time)
+	for rows.Next() {
+		var campaignID string
+		var shownAt time.Time
+		if err := rows.Scan(&campaignID, &shownAt); err != nil {
+			return nil, err
+		}
+		exposures[campaignID] = append(exposures[campaignID], shownAt)
+	}
+
+	return exposures, rows.Err()
+}
+
+func (s *PostgresFrequencyStore) Record(ctx context.Context, deviceID string, caps map[string][]frequency.Cap, at time.Time) (map[string]bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Concurrent records for the device wait here, so each one counts the
+	// exposures the previous one added.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('frequency:' || $1))`, deviceID); err != nil {
+		return nil, err
+	}
+
+	campaignIDs := make([]string, 0, len(caps))
+	for id := range caps {
+		campaignIDs = append(campaignIDs, id)
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT campaign_id, shown_at
+		FROM frequency_exposures
+		WHERE device_id = $1 AND campaign_id = ANY($2) AND shown_at >= $3
+	`, deviceID, pq.StringArray(campaignIDs), at.Add(-frequency.Retention))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	exposures := make(map[string][]time.Time)
+	for rows.Next() {
+		var campaignID string
+		var shownAt time.Time
+		if err := rows.Scan(&campaignID, &shownAt); err != nil {
+			return nil, err
+		}
+		exposures[campaignID] = append(exposures[campaignID], shownAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[string]bool, len(caps))
+	var allowed []string
+	for id, campaignCaps := range caps {
+		if frequency.Allows(campaignCaps, exposures[id], at) {
+			recorded[id] = true
+			allowed = append(allowed, id)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO frequency_exposures (device_id, campaign_id, shown_at)
+		SELECT $1, unnest($2::text[]), $3
+	`, deviceID, pq.StringArray(allowed), at)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expire the device's old exposures while we are here.
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM frequency_exposures
+		WHERE device_id = $1 AND shown_at < $2
+	`, deviceID, at.Add(-frequency.Retention))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
@@ -0,0 +1,579 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"targeting-engine/internal/models"
+)
+
+// MemoryRepository keeps campaigns, rules, expressions and creatives in memory. It is
+// safe for concurrent use, behaves like PostgresRepository, errors included,
+// and pushes a change event for every write to its listeners. Everything is
+// lost on exit.
+type MemoryRepository struct {
+	mu sync.RWMutex
+	// ids keeps campaigns in the order they were created.
+	ids         []string
+	campaigns   map[string]models.Campaign
+	rules       map[RuleKey]models.TargetingRule
+	expressions map[string]models.TargetingExpression
+	creatives   map[creativeKey]models.Creative
+	listeners   map[*memoryListener]bool
+}
+
+type creativeKey struct {
+	campaignID, id string
+}
+
+var (
+	_ Repository = (*MemoryRepository)(nil)
+	_ Notifier   = (*MemoryRepository)(nil)
+)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		campaigns:   make(map[string]models.Campaign),
+		rules:       make(map[RuleKey]models.TargetingRule),
+		expressions: make(map[string]models.TargetingExpression),
+		creatives:   make(map[creativeKey]models.Creative),
+		listeners:   make(map[*memoryListener]bool),
+	}
+}
+
+func (r *MemoryRepository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	campaigns := make([]models.Campaign, 0, len(r.ids))
+	for _, id := range r.ids {
+		campaigns = append(campaigns, r.campaigns[id])
+	}
+	return campaigns, nil
+}
+
+func (r *MemoryRepository) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	campaign, ok := r.campaigns[id]
+	if !ok {
+		return nil, ErrCampaignNotFound
+	}
+	return &campaign, nil
+}
+
+func (r *MemoryRepository) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]models.Campaign, error) {
+	campaigns, _ := r.GetCampaigns(ctx)
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].ID < campaigns[j].ID })
+
+	name := strings.ToLower(filter.Name)
+	var matched []models.Campaign
+	for _, c := range campaigns {
+		if filter.Status != "" && c.Status != filter.Status {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(c.Name), name) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+
+	if filter.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
+func (r *MemoryRepository) CreateCampaign(ctx context.Context, campaign models.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[campaign.ID]; ok {
+		return ErrCampaignExists
+	}
+	r.saveCampaign(campaign)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateCampaign(ctx context.Context, campaign models.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[campaign.ID]; !ok {
+		return ErrCampaignNotFound
+	}
+	r.saveCampaign(campaign)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateCampaignStatus(ctx context.Context, id string, status models.Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaign, ok := r.campaigns[id]
+	if !ok {
+		return ErrCampaignNotFound
+	}
+	campaign.Status = status
+	r.saveCampaign(campaign)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteCampaign(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[id]; !ok {
+		return ErrCampaignNotFound
+	}
+	r.deleteCampaign(id)
+	return nil
+}
+
+// SaveCampaign creates or replaces a campaign.
+func (r *MemoryRepository) SaveCampaign(ctx context.Context, campaign models.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saveCampaign(campaign)
+	return nil
+}
+
+func (r *MemoryRepository) GetTargetingRules(ctx context.Context) ([]models.TargetingRule, error) {
+	return r.rulesWhere(func(RuleKey) bool { return true }), nil
+}
+
+func (r *MemoryRepository) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]models.TargetingRule, error) {
+	return r.rulesWhere(func(key RuleKey) bool { return key.CampaignID == campaignID }), nil
+}
+
+func (r *MemoryRepository) CreateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[rule.CampaignID]; !ok {
+		return ErrCampaignNotFound
+	}
+	if _, ok := r.rules[ruleKey(rule)]; ok {
+		return ErrRuleExists
+	}
+	r.saveRule(rule)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rules[ruleKey(rule)]; !ok {
+		return ErrRuleNotFound
+	}
+	r.saveRule(rule)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteTargetingRule(ctx context.Context, campaignID string, dimension models.DimensionType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := RuleKey{CampaignID: campaignID, Dimension: dimension}
+	if _, ok := r.rules[key]; !ok {
+		return ErrRuleNotFound
+	}
+	delete(r.rules, key)
+	r.publish(campaignID)
+	return nil
+}
+
+// SaveTargetingRule creates or replaces the rule for a campaign's dimension.
+func (r *MemoryRepository) SaveTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[rule.CampaignID]; !ok {
+		return ErrCampaignNotFound
+	}
+	r.saveRule(rule)
+	return nil
+}
+
+func (r *MemoryRepository) GetTargetingExpressions(ctx context.Context) ([]models.TargetingExpression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var expressions []models.TargetingExpression
+	for _, id := range r.ids {
+		if expr, ok := r.expressions[id]; ok {
+			expressions = append(expressions, expr)
+		}
+	}
+	return expressions, nil
+}
+
+func (r *MemoryRepository) GetTargetingExpressionByCampaignID(ctx context.Context, campaignID string) (*models.TargetingExpression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expr, ok := r.expressions[campaignID]
+	if !ok {
+		return nil, ErrExpressionNotFound
+	}
+	return &expr, nil
+}
+
+func (r *MemoryRepository) SaveTargetingExpression(ctx context.Context, expr models.TargetingExpression) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[expr.CampaignID]; !ok {
+		return ErrCampaignNotFound
+	}
+	r.expressions[expr.CampaignID] = expr
+	r.publish(expr.CampaignID)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteTargetingExpression(ctx context.Context, campaignID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.expressions[campaignID]; !ok {
+		return ErrExpressionNotFound
+	}
+	delete(r.expressions, campaignID)
+	r.publish(campaignID)
+	return nil
+}
+
+func (r *MemoryRepository) GetCreatives(ctx context.Context) ([]models.Creative, error) {
+	return r.creativesWhere(func(creativeKey) bool { return true }), nil
+}
+
+func (r *MemoryRepository) GetCreativesByCampaignID(ctx context.Context, campaignID string) ([]models.Creative, error) {
+	return r.creativesWhere(func(key creativeKey) bool { return key.campaignID == campaignID }), nil
+}
+
+func (r *MemoryRepository) CreateCreative(ctx context.Context, creative models.Creative) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[creative.CampaignID]; !ok {
+		return ErrCampaignNotFound
+	}
+	key := creativeKey{campaignID: creative.CampaignID, id: creative.ID}
+	if _, ok := r.creatives[key]; ok {
+		return ErrCreativeExists
+	}
+	r.creatives[key] = creative
+	r.publish(creative.CampaignID)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateCreative(ctx context.Context, creative models.Creative) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := creativeKey{campaignID: creative.CampaignID, id: creative.ID}
+	if _, ok := r.creatives[key]; !ok {
+		return ErrCreativeNotFound
+	}
+	r.creatives[key] = creative
+	r.publish(creative.CampaignID)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteCreative(ctx context.Context, campaignID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := creativeKey{campaignID: campaignID, id: id}
+	if _, ok := r.creatives[key]; !ok {
+		return ErrCreativeNotFound
+	}
+	delete(r.creatives, key)
+	r.publish(campaignID)
+	return nil
+}
+
+// SaveCreative creates or replaces a creative.
+func (r *MemoryRepository) SaveCreative(ctx context.Context, creative models.Creative) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.campaigns[creative.CampaignID]; !ok {
+		return ErrCampaignNotFound
+	}
+	r.creatives[creativeKey{campaignID: creative.CampaignID, id: creative.ID}] = creative
+	r.publish(creative.CampaignID)
+	return nil
+}
+
+func (r *MemoryRepository) ApplyBatch(ctx context.Context, batch Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check everything first so a failing batch changes nothing.
+	saved := make(map[string]bool, len(batch.SaveCampaigns))
+	for _, c := range batch.SaveCampaigns {
+		saved[c.ID] = true
+	}
+	for _, rule := range batch.SaveRules {
+		if _, ok := r.campaigns[rule.CampaignID]; !ok && !saved[rule.CampaignID] {
+			return fmt.Errorf("saving %s rule of campaign %s: %w", rule.DimensionType, rule.CampaignID, ErrCampaignNotFound)
+		}
+	}
+	for _, expr := range batch.SaveExpressions {
+		if _, ok := r.campaigns[expr.CampaignID]; !ok && !saved[expr.CampaignID] {
+			return fmt.Errorf("saving expression of campaign %s: %w", expr.CampaignID, ErrCampaignNotFound)
+		}
+	}
+	for _, c := range batch.SaveCreatives {
+		if _, ok := r.campaigns[c.CampaignID]; !ok && !saved[c.CampaignID] {
+			return fmt.Errorf("saving creative %s of campaign %s: %w", c.ID, c.CampaignID, ErrCampaignNotFound)
+		}
+	}
+
+	for _, c := range batch.SaveCampaigns {
+		r.saveCampaign(c)
+	}
+	for _, key := range batch.DeleteRules {
+		delete(r.rules, key)
+		r.publish(key.CampaignID)
+	}
+	for _, rule := range batch.SaveRules {
+		r.saveRule(rule)
+	}
+	for _, expr := range batch.SaveExpressions {
+		r.expressions[expr.CampaignID] = expr
+		r.publish(expr.CampaignID)
+	}
+	for _, c := range batch.SaveCreatives {
+		r.creatives[creativeKey{campaignID: c.CampaignID, id: c.ID}] = c
+		r.publish(c.CampaignID)
+	}
+	for _, id := range batch.DeleteCampaigns {
+		if _, ok := r.campaigns[id]; ok {
+			r.deleteCampaign(id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+// saveCampaign creates or replaces a campaign. r.mu must be held.
+func (r *MemoryRepository) saveCampaign(campaign models.Campaign) {
+	if _, ok := r.campaigns[campaign.ID]; !ok {
+		r.ids = append(r.ids, campaign.ID)
+	}
+	r.campaigns[campaign.ID] = campaign
+	r.publish(campaign.ID)
+}
+
+// deleteCampaign deletes a campaign with its rules, expression and
+// creatives, as the foreign keys do in PostgreSQL. r.mu must be held.
+func (r *MemoryRepository) deleteCampaign(id string) {
+	delete(r.campaigns, id)
+	for i, existing := range r.ids {
+		if existing == id {
+			r.ids = append(r.ids[:i], r.ids[i+1:]...)
+			break
+		}
+	}
+	for key := range r.rules {
+		if key.CampaignID == id {
+			delete(r.rules, key)
+		}
+	}
+	delete(r.expressions, id)
+	for key := range r.creatives {
+		if key.campaignID == id {
+			delete(r.creatives, key)
+		}
+	}
+	r.publish(id)
+}
+
+// saveRule creates or replaces a rule. r.mu must be held.
+func (r *MemoryRepository) saveRule(rule models.TargetingRule) {
+	rule.MatchType = rule.MatchType.OrDefault()
+	r.rules[ruleKey(rule)] = rule
+	r.publish(rule.CampaignID)
+}
+
+// replace swaps in the contents of next and tells listeners about every
+// campaign that changed, as if it had been written piece by piece. next must
+// not be used afterwards.
+func (r *MemoryRepository) replace(next *MemoryRepository) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := append(append([]string{}, r.ids...), next.ids...)
+	changed := make(map[string]bool)
+	for _, id := range ids {
+		if changed[id] {
+			continue
+		}
+		old, oldOK := r.campaigns[id]
+		c, ok := next.campaigns[id]
+		oldExpr, oldExprOK := r.expressions[id]
+		expr, exprOK := next.expressions[id]
+		if oldOK != ok || !reflect.DeepEqual(old, c) || oldExprOK != exprOK || !reflect.DeepEqual(oldExpr, expr) ||
+			!reflect.DeepEqual(r.campaignCreatives(id), next.campaignCreatives(id)) {
+			changed[id] = true
+			continue
+		}
+		for _, dimension := range models.Dimensions {
+			key := RuleKey{CampaignID: id, Dimension: dimension}
+			oldRule, oldRuleOK := r.rules[key]
+			rule, ruleOK := next.rules[key]
+			if oldRuleOK != ruleOK || !reflect.DeepEqual(oldRule, rule) {
+				changed[id] = true
+				break
+			}
+		}
+	}
+
+	r.ids, r.campaigns, r.rules, r.expressions, r.creatives = next.ids, next.campaigns, next.rules, next.expressions, next.creatives
+	for _, id := range ids {
+		if changed[id] {
+			r.publish(id)
+			delete(changed, id)
+		}
+	}
+}
+
+// rulesWhere returns the rules whose key matches, ordered by campaign
+// creation and then dimension.
+func (r *MemoryRepository) rulesWhere(match func(RuleKey) bool) []models.TargetingRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var rules []models.TargetingRule
+	for _, id := range r.ids {
+		for _, dimension := range models.Dimensions {
+			key := RuleKey{CampaignID: id, Dimension: dimension}
+			if rule, ok := r.rules[key]; ok && match(key) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// creativesWhere returns the creatives whose key matches, ordered by
+// campaign creation and then ID.
+func (r *MemoryRepository) creativesWhere(match func(creativeKey) bool) []models.Creative {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var creatives []models.Creative
+	for _, id := range r.ids {
+		for _, c := range r.campaignCreatives(id) {
+			if match(creativeKey{campaignID: c.CampaignID, id: c.ID}) {
+				creatives = append(creatives, c)
+			}
+		}
+	}
+	return creatives
+}
+
+// campaignCreatives returns a campaign's creatives ordered by ID. r.mu must
+// be held.
+func (r *MemoryRepository) campaignCreatives(campaignID string) []models.Creative {
+	var creatives []models.Creative
+	for key, c := range r.creatives {
+		if key.campaignID == campaignID {
+			creatives = append(creatives, c)
+		}
+	}
+	sort.Slice(creatives, func(i, j int) bool { return creatives[i].ID < creatives[j].ID })
+	return creatives
+}
+
+func ruleKey(rule models.TargetingRule) RuleKey {
+	return RuleKey{CampaignID: rule.CampaignID, Dimension: rule.DimensionType}
+}
+
+// memoryListener collects the campaigns changed since its events were last
+// delivered, so slow readers never block writers or miss a change.
+type memoryListener struct {
+	pending map[string]bool
+	wake    chan struct{}
+}
+
+// Listen streams a ChangeRow event for every write until ctx is done. The
+// first event is a ChangeResync.
+func (r *MemoryRepository) Listen(ctx context.Context) (<-chan ChangeEvent, error) {
+	l := &memoryListener{pending: make(map[string]bool), wake: make(chan struct{}, 1)}
+	r.mu.Lock()
+	r.listeners[l] = true
+	r.mu.Unlock()
+
+	events := make(chan ChangeEvent, 64)
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.listeners, l)
+			r.mu.Unlock()
+		}()
+
+		send := func(ev ChangeEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if !send(ChangeEvent{Kind: ChangeResync}) {
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-l.wake:
+			}
+
+			r.mu.Lock()
+			changed := l.pending
+			l.pending = make(map[string]bool)
+			r.mu.Unlock()
+
+			for id := range changed {
+				if !send(ChangeEvent{Kind: ChangeRow, CampaignID: id}) {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// publish tells every listener that a campaign changed. r.mu must be held.
+func (r *MemoryRepository) publish(campaignID string) {
+	for l := range r.listeners {
+		l.pending[campaignID] = true
+		select {
+		case l.wake <- struct{}{}:
+		default:
+		}
+	}
+}
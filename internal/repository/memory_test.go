@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/fixtures"
+	"targeting-engine/internal/models"
+)
+
+func TestMemoryRepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	campaign := models.Campaign{ID: "spotify", Name: "Spotify", Status: models.StatusActive}
+	rule := models.TargetingRule{CampaignID: "spotify", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"iOS"}}
+	creative := models.Creative{CampaignID: "spotify", ID: "banner", Type: models.CreativeImage, URL: "https://banner"}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "Rule without campaign", err: repo.CreateTargetingRule(ctx, rule), want: ErrCampaignNotFound},
+		{name: "Creative without campaign", err: repo.CreateCreative(ctx, creative), want: ErrCampaignNotFound},
+		{name: "Update missing campaign", err: repo.UpdateCampaign(ctx, campaign), want: ErrCampaignNotFound},
+		{name: "Create campaign", err: repo.CreateCampaign(ctx, campaign)},
+		{name: "Create campaign twice", err: repo.CreateCampaign(ctx, campaign), want: ErrCampaignExists},
+		{name: "Update missing rule", err: repo.UpdateTargetingRule(ctx, rule), want: ErrRuleNotFound},
+		{name: "Create rule", err: repo.CreateTargetingRule(ctx, rule)},
+		{name: "Create rule twice", err: repo.CreateTargetingRule(ctx, rule), want: ErrRuleExists},
+		{name: "Update missing creative", err: repo.UpdateCreative(ctx, creative), want: ErrCreativeNotFound},
+		{name: "Create creative", err: repo.CreateCreative(ctx, creative)},
+		{name: "Create creative twice", err: repo.CreateCreative(ctx, creative), want: ErrCreativeExists},
+		{name: "Missing expression", err: repo.DeleteTargetingExpression(ctx, "spotify"), want: ErrExpressionNotFound},
+		{name: "Delete missing campaign", err: repo.DeleteCampaign(ctx, "duolingo"), want: ErrCampaignNotFound},
+	}
+	for _, tc := range tests {
+		if !errors.Is(tc.err, tc.want) {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.want, tc.err)
+		}
+	}
+
+	rules, _ := repo.GetTargetingRulesByCampaignID(ctx, "spotify")
+	if len(rules) != 1 || rules[0].MatchType != models.MatchExact {
+		t.Errorf("Expected the rule stored with its default match type but got %+v", rules)
+	}
+
+	if err := repo.DeleteCampaign(ctx, "spotify"); err != nil {
+		t.Fatalf("DeleteCampaign failed: %v", err)
+	}
+	if rules, _ := repo.GetTargetingRules(ctx); len(rules) != 0 {
+		t.Errorf("Expected deleting a campaign to delete its rules but got %+v", rules)
+	}
+	if creatives, _ := repo.GetCreatives(ctx); len(creatives) != 0 {
+		t.Errorf("Expected deleting a campaign to delete its creatives but got %+v", creatives)
+	}
+}
+
+func TestMemoryRepositoryListCampaigns(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	for _, c := range []models.Campaign{
+		{ID: "c", Name: "Candy Crush", Status: models.StatusActive},
+		{ID: "a", Name: "Spotify", Status: models.StatusActive},
+		{ID: "b", Name: "Spotify Kids", Status: models.StatusInactive},
+	} {
+		repo.SaveCampaign(ctx, c)
+	}
+
+	tests := []struct {
+		name   string
+		filter CampaignFilter
+		want   []string
+	}{
+		{name: "Everything by ID", want: []string{"a", "b", "c"}},
+		{name: "By status", filter: CampaignFilter{Status: models.StatusActive}, want: []string{"a", "c"}},
+		{name: "By name", filter: CampaignFilter{Name: "spotify"}, want: []string{"a", "b"}},
+		{name: "Paged", filter: CampaignFilter{Limit: 1, Offset: 1}, want: []string{"b"}},
+		{name: "Past the end", filter: CampaignFilter{Offset: 3}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			campaigns, err := repo.ListCampaigns(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("ListCampaigns failed: %v", err)
+			}
+			var got []string
+			for _, c := range campaigns {
+				got = append(got, c.ID)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Expected %v but got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("Expected %v but got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryRepositoryApplyBatch(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	repo.SaveCampaign(ctx, models.Campaign{ID: "old", Status: models.StatusActive})
+
+	failing := Batch{
+		SaveCampaigns: []models.Campaign{{ID: "new", Status: models.StatusActive}},
+		SaveRules:     []models.TargetingRule{{CampaignID: "missing", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"iOS"}}},
+	}
+	if err := repo.ApplyBatch(ctx, failing); !errors.Is(err, ErrCampaignNotFound) {
+		t.Fatalf("Expected ErrCampaignNotFound but got %v", err)
+	}
+	if _, err := repo.GetCampaignByID(ctx, "new"); !errors.Is(err, ErrCampaignNotFound) {
+		t.Error("Expected a failing batch to change nothing")
+	}
+
+	batch := Batch{
+		SaveCampaigns:   []models.Campaign{{ID: "new", Status: models.StatusActive}},
+		SaveRules:       []models.TargetingRule{{CampaignID: "new", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"iOS"}}},
+		DeleteCampaigns: []string{"old"},
+	}
+	if err := repo.ApplyBatch(ctx, batch); err != nil {
+		t.Fatalf("ApplyBatch failed: %v", err)
+	}
+	campaigns, _ := repo.GetCampaigns(ctx)
+	rules, _ := repo.GetTargetingRules(ctx)
+	if len(campaigns) != 1 || campaigns[0].ID != "new" || len(rules) != 1 {
+		t.Errorf("Expected only the new campaign and its rule but got %+v and %+v", campaigns, rules)
+	}
+}
+
+func TestFixturesBatchIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	f, err := fixtures.Parse([]byte(`
+campaigns:
+  - {id: a, name: A, image_url: https://a, cta: Go, status: ACTIVE}
+  - {id: b, name: B, image_url: https://b, cta: Go, status: ACTIVE}
+rules:
+  - {campaign_id: a, dimension_type: COUNTRY, rule_type: INCLUDE, values: [US]}
+  - {campaign_id: a, dimension_type: OS, rule_type: EXCLUDE, values: [web]}
+expressions:
+  - {campaign_id: b, expression: {dimension: OS, values: [iOS]}}
+creatives:
+  - {campaign_id: b, id: banner, type: IMAGE, url: https://banner}
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := repo.ApplyBatch(ctx, FixturesBatch(f)); err != nil {
+			t.Fatalf("ApplyBatch %d failed: %v", i+1, err)
+		}
+	}
+
+	campaigns, _ := repo.GetCampaigns(ctx)
+	rules, _ := repo.GetTargetingRules(ctx)
+	expressions, _ := repo.GetTargetingExpressions(ctx)
+	creatives, _ := repo.GetCreatives(ctx)
+	if len(campaigns) != 2 || len(rules) != 2 || len(expressions) != 1 || len(creatives) != 1 {
+		t.Errorf("Expected 2 campaigns, 2 rules, 1 expression and 1 creative after applying twice but got %+v, %+v, %+v and %+v", campaigns, rules, expressions, creatives)
+	}
+
+	// A creative of an unknown campaign fails the whole batch.
+	f.Campaigns = append(f.Campaigns, models.Campaign{ID: "c", Status: models.StatusActive})
+	f.Creatives = append(f.Creatives, models.Creative{CampaignID: "missing", ID: "banner", Type: models.CreativeImage, URL: "https://banner"})
+	if err := repo.ApplyBatch(ctx, FixturesBatch(f)); !errors.Is(err, ErrCampaignNotFound) {
+		t.Fatalf("Expected ErrCampaignNotFound but got %v", err)
+	}
+	if _, err := repo.GetCampaignByID(ctx, "c"); !errors.Is(err, ErrCampaignNotFound) {
+		t.Error("Expected a failing batch to change nothing")
+	}
+}
+
+func TestMemoryRepositoryListen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := NewMemoryRepository()
+
+	events, err := repo.Listen(ctx)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	next := func() ChangeEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for a change event")
+			return ChangeEvent{}
+		}
+	}
+	if ev := next(); ev.Kind != ChangeResync {
+		t.Fatalf("Expected a resync first but got %+v", ev)
+	}
+
+	// Concurrent writers never block on the listener.
+	var wg sync.WaitGroup
+	for _, id := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				repo.SaveCampaign(ctx, models.Campaign{ID: id, Status: models.StatusActive})
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for len(seen) < 3 {
+		ev := next()
+		if ev.Kind != ChangeRow {
+			t.Fatalf("Expected row changes but got %+v", ev)
+		}
+		seen[ev.CampaignID] = true
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Embedded migrations don't load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("Expected migration %d but got %d_%s; versions must not skip", i+1, m.Version, m.Name)
+		}
+	}
+	if !strings.Contains(migrations[1].Up, changeChannel) {
+		t.Errorf("Expected migration 0002 to notify on %s", changeChannel)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []int
+		wantErr string
+	}{
+		{
+			name: "Sorted by version",
+			files: fstest.MapFS{
+				"0010_later.up.sql":   file("up"),
+				"0010_later.down.sql": file("down"),
+				"0002_first.up.sql":   file("up"),
+				"0002_first.down.sql": file("down"),
+			},
+			want: []int{2, 10},
+		},
+		{
+			name:    "Missing down",
+			files:   fstest.MapFS{"0001_init.up.sql": file("up")},
+			wantErr: "needs both",
+		},
+		{
+			name: "Conflicting names",
+			files: fstest.MapFS{
+				"0001_init.up.sql":  file("up"),
+				"0001_other.up.sql": file("up"),
+			},
+			wantErr: "two names",
+		},
+		{
+			name:    "No direction",
+			files:   fstest.MapFS{"0001_init.sql": file("up")},
+			wantErr: ".up.sql or .down.sql",
+		},
+		{
+			name:    "No version",
+			files:   fstest.MapFS{"init.up.sql": file("up")},
+			wantErr: "positive version",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tc.files)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Expected an error containing %q but got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(migrations) != len(tc.want) {
+				t.Fatalf("Expected %d migrations but got %d", len(tc.want), len(migrations))
+			}
+			for i, version := range tc.want {
+				if migrations[i].Version != version {
+					t.Errorf("Expected version %d at %d but got %d", version, i, migrations[i].Version)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS targeting_rules;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+	id VARCHAR(255) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	image_url VARCHAR(255) NOT NULL,
+	cta VARCHAR(255) NOT NULL,
+	status VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS targeting_rules (
+	campaign_id VARCHAR(255) NOT NULL,
+	dimension_type VARCHAR(255) NOT NULL,
+	rule_type VARCHAR(255) NOT NULL,
+	values TEXT[] NOT NULL,
+	PRIMARY KEY (campaign_id, dimension_type),
+	FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS targeting_rules_notify ON targeting_rules;
+DROP TRIGGER IF EXISTS campaigns_notify ON campaigns;
+DROP FUNCTION IF EXISTS notify_targeting_change();
@@ -0,0 +1,34 @@
+-- Publish every campaign and rule write on the targeting_changes channel so
+-- running instances can refresh their targeting data without polling.
+CREATE OR REPLACE FUNCTION notify_targeting_change() RETURNS trigger AS $$
+DECLARE
+	old_id TEXT;
+	new_id TEXT;
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		old_id := to_jsonb(OLD) ->> TG_ARGV[0];
+		PERFORM pg_notify('targeting_changes', json_build_object(
+			'table', TG_TABLE_NAME, 'op', TG_OP, 'campaign_id', old_id
+		)::text);
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		new_id := to_jsonb(NEW) ->> TG_ARGV[0];
+		IF new_id IS DISTINCT FROM old_id THEN
+			PERFORM pg_notify('targeting_changes', json_build_object(
+				'table', TG_TABLE_NAME, 'op', TG_OP, 'campaign_id', new_id
+			)::text);
+		END IF;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS campaigns_notify ON campaigns;
+CREATE TRIGGER campaigns_notify
+	AFTER INSERT OR UPDATE OR DELETE ON campaigns
+	FOR EACH ROW EXECUTE FUNCTION notify_targeting_change('id');
+
+DROP TRIGGER IF EXISTS targeting_rules_notify ON targeting_rules;
+CREATE TRIGGER targeting_rules_notify
+	AFTER INSERT OR UPDATE OR DELETE ON targeting_rules
+	FOR EACH ROW EXECUTE FUNCTION notify_targeting_change('campaign_id');
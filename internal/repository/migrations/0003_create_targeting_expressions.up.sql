@@ -0,0 +1,11 @@
+-- Boolean targeting expressions, at most one per campaign.
+CREATE TABLE IF NOT EXISTS targeting_expressions (
+	campaign_id VARCHAR(255) PRIMARY KEY,
+	expression JSONB NOT NULL,
+	FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE
+);
+
+DROP TRIGGER IF EXISTS targeting_expressions_notify ON targeting_expressions;
+CREATE TRIGGER targeting_expressions_notify
+	AFTER INSERT OR UPDATE OR DELETE ON targeting_expressions
+	FOR EACH ROW EXECUTE FUNCTION notify_targeting_change('campaign_id');
@@ -0,0 +1,2 @@
+ALTER TABLE targeting_rules
+DROP COLUMN IF EXISTS match_type;
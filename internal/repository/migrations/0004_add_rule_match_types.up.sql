@@ -0,0 +1,2 @@
+ALTER TABLE targeting_rules
+ADD COLUMN IF NOT EXISTS match_type VARCHAR(32) NOT NULL DEFAULT 'EXACT';
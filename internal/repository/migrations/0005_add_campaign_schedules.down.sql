@@ -0,0 +1,5 @@
+ALTER TABLE campaigns
+DROP COLUMN IF EXISTS dayparts,
+DROP COLUMN IF EXISTS time_zone,
+DROP COLUMN IF EXISTS end_at,
+DROP COLUMN IF EXISTS start_at;
@@ -0,0 +1,6 @@
+-- Flight dates and dayparting.
+ALTER TABLE campaigns
+ADD COLUMN IF NOT EXISTS start_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS end_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+ADD COLUMN IF NOT EXISTS dayparts JSONB NOT NULL DEFAULT '[]';
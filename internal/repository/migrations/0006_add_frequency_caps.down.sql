@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS frequency_exposures;
+
+ALTER TABLE campaigns
+DROP COLUMN IF EXISTS frequency_caps;
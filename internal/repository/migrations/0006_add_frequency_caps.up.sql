@@ -0,0 +1,10 @@
+ALTER TABLE campaigns
+ADD COLUMN IF NOT EXISTS frequency_caps JSONB NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS frequency_exposures (
+	device_id VARCHAR(255) NOT NULL,
+	campaign_id VARCHAR(255) NOT NULL,
+	shown_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS frequency_exposures_device_idx
+	ON frequency_exposures (device_id, campaign_id, shown_at);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS campaign_budget_usage;
+
+ALTER TABLE campaigns
+DROP COLUMN IF EXISTS pacing,
+DROP COLUMN IF EXISTS lifetime_spend_budget,
+DROP COLUMN IF EXISTS daily_spend_budget,
+DROP COLUMN IF EXISTS lifetime_impression_budget,
+DROP COLUMN IF EXISTS daily_impression_budget,
+DROP COLUMN IF EXISTS bid_cpm;
@@ -0,0 +1,16 @@
+-- Budgets and pacing.
+ALTER TABLE campaigns
+ADD COLUMN IF NOT EXISTS bid_cpm DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS daily_impression_budget BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS lifetime_impression_budget BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS daily_spend_budget DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS lifetime_spend_budget DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS pacing VARCHAR(16) NOT NULL DEFAULT 'ASAP';
+
+CREATE TABLE IF NOT EXISTS campaign_budget_usage (
+	campaign_id VARCHAR(255) NOT NULL,
+	day DATE NOT NULL,
+	impressions BIGINT NOT NULL DEFAULT 0,
+	spend DOUBLE PRECISION NOT NULL DEFAULT 0,
+	PRIMARY KEY (campaign_id, day)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE campaigns
+DROP COLUMN IF EXISTS weight,
+DROP COLUMN IF EXISTS priority;
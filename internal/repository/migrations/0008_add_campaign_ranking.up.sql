@@ -0,0 +1,4 @@
+-- Ranking of campaigns in delivery responses.
+ALTER TABLE campaigns
+ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 0;
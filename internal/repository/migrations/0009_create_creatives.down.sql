@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS creatives;
+
+ALTER TABLE campaigns
+DROP COLUMN IF EXISTS creative_rotation;
@@ -0,0 +1,23 @@
+-- Creatives, the ads a campaign rotates between.
+ALTER TABLE campaigns
+ADD COLUMN IF NOT EXISTS creative_rotation VARCHAR(16) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS creatives (
+	campaign_id VARCHAR(255) NOT NULL,
+	id VARCHAR(255) NOT NULL,
+	type VARCHAR(16) NOT NULL,
+	url TEXT NOT NULL DEFAULT '',
+	html TEXT NOT NULL DEFAULT '',
+	width INTEGER NOT NULL DEFAULT 0,
+	height INTEGER NOT NULL DEFAULT 0,
+	cta VARCHAR(255) NOT NULL DEFAULT '',
+	locale VARCHAR(35) NOT NULL DEFAULT '',
+	weight INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (campaign_id, id),
+	FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE
+);
+
+DROP TRIGGER IF EXISTS creatives_notify ON creatives;
+CREATE TRIGGER creatives_notify
+	AFTER INSERT OR UPDATE OR DELETE ON creatives
+	FOR EACH ROW EXECUTE FUNCTION notify_targeting_change('campaign_id');
@@ -0,0 +1,3 @@
+-- A/B experiments splitting a campaign's traffic between arms.
+ALTER TABLE campaigns
+ADD COLUMN IF NOT EXISTS experiment JSONB;
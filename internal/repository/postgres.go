@@ -3,20 +3,30 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"targeting-engine/internal/models"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-var ErrCampaignNotFound = errors.New("campaign not found")
+// changeChannel is the NOTIFY channel the campaign and rule triggers publish
+// to. The triggers are created by migration 0002, which names it too.
+const changeChannel = "targeting_changes"
 
 type PostgresRepository struct {
-	db *sql.DB
+	db  *tracedDB
+	uri string
 }
 
+// NewPostgresRepository connects to PostgreSQL. It doesn't touch the schema;
+// run MigrateUp, or the migrate command, for that.
 func NewPostgresRepository(ctx context.Context, uri string) (*PostgresRepository, error) {
 	db, err := sql.Open("postgres", uri)
 	if err != nil {
@@ -26,46 +36,59 @@ func NewPostgresRepository(ctx context.Context, uri string) (*PostgresRepository
 		return nil, fmt.Errorf("couldn't ping PostgreSQL: %v", err)
 	}
 
-	if err := createTables(ctx, db); err != nil {
-		return nil, fmt.Errorf("couldn't create tables: %v", err)
-	}
+	return &PostgresRepository{db: &tracedDB{db}, uri: uri}, nil
+}
 
-	return &PostgresRepository{db: db}, nil
+// campaignColumns lists the campaign columns in the order scanCampaign reads
+// and campaignArgs writes them.
+var campaignColumns = []string{
+	"id", "name", "image_url", "cta", "status", "priority", "weight", "creative_rotation",
+	"start_at", "end_at", "time_zone", "dayparts",
+	"frequency_caps", "bid_cpm", "daily_impression_budget", "lifetime_impression_budget",
+	"daily_spend_budget", "lifetime_spend_budget", "pacing", "experiment",
 }
 
-func createTables(ctx context.Context, db *sql.DB) error {
-	_, err := db.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS campaigns (
-			id VARCHAR(255) PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			image_url VARCHAR(255) NOT NULL,
-			cta VARCHAR(255) NOT NULL,
-			status VARCHAR(255) NOT NULL
-		)
-	`)
-	if err != nil {
-		return err
-	}
+var (
+	campaignColumnList   = strings.Join(campaignColumns, ", ")
+	campaignPlaceholders = placeholders(1, len(campaignColumns))
+	campaignAssignments  = assignments(campaignColumns[1:], 2)
 
-	// Create targeting rules table
-	_, err = db.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS targeting_rules (
-			campaign_id VARCHAR(255) NOT NULL,
-			dimension_type VARCHAR(255) NOT NULL,
-			rule_type VARCHAR(255) NOT NULL,
-			values TEXT[] NOT NULL,
-			PRIMARY KEY (campaign_id, dimension_type),
-			FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE
-		)
-	`)
-	return err
+	upsertCampaignQuery = `
+		INSERT INTO campaigns (` + campaignColumnList + `)
+		VALUES (` + campaignPlaceholders + `)
+		ON CONFLICT (id) DO UPDATE
+		SET ` + campaignAssignments
+)
+
+const upsertRuleQuery = `
+	INSERT INTO targeting_rules (campaign_id, dimension_type, rule_type, match_type, values)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (campaign_id, dimension_type) DO UPDATE
+	SET rule_type = $3, match_type = $4, values = $5
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func (r *PostgresRepository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, name, image_url, cta, status
-		FROM campaigns
-	`)
+func scanCampaign(row rowScanner) (models.Campaign, error) {
+	var c models.Campaign
+	err := row.Scan(&c.ID, &c.Name, &c.ImageURL, &c.CTA, &c.Status, &c.Priority, &c.Weight, &c.CreativeRotation,
+		&c.StartAt, &c.EndAt, &c.TimeZone, &c.Dayparts,
+		&c.FrequencyCaps, &c.BidCPM, &c.DailyImpressionBudget, &c.LifetimeImpressionBudget,
+		&c.DailySpendBudget, &c.LifetimeSpendBudget, &c.Pacing, &c.Experiment)
+	return c, err
+}
+
+func campaignArgs(c models.Campaign) []interface{} {
+	return []interface{}{c.ID, c.Name, c.ImageURL, c.CTA, c.Status, c.Priority, c.Weight, c.CreativeRotation,
+		c.StartAt, c.EndAt, c.TimeZone, c.Dayparts,
+		c.FrequencyCaps, c.BidCPM, c.DailyImpressionBudget, c.LifetimeImpressionBudget,
+		c.DailySpendBudget, c.LifetimeSpendBudget, c.Pacing, c.Experiment}
+}
+
+func (r *PostgresRepository) queryCampaigns(ctx context.Context, query string, args ...interface{}) ([]models.Campaign, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,8 +96,8 @@ func (r *PostgresRepository) GetCampaigns(ctx context.Context) ([]models.Campaig
 
 	var campaigns []models.Campaign
 	for rows.Next() {
-		var c models.Campaign
-		if err := rows.Scan(&c.ID, &c.Name, &c.ImageURL, &c.CTA, &c.Status); err != nil {
+		c, err := scanCampaign(rows)
+		if err != nil {
 			return nil, err
 		}
 		campaigns = append(campaigns, c)
@@ -83,19 +106,94 @@ func (r *PostgresRepository) GetCampaigns(ctx context.Context) ([]models.Campaig
 	return campaigns, rows.Err()
 }
 
-func (r *PostgresRepository) SaveCampaign(ctx context.Context, campaign models.Campaign) error {
+func (r *PostgresRepository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	return r.queryCampaigns(ctx, `
+		SELECT `+campaignColumnList+`
+		FROM campaigns
+	`)
+}
+
+func (r *PostgresRepository) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
+	c, err := scanCampaign(r.db.QueryRowContext(ctx, `
+		SELECT `+campaignColumnList+`
+		FROM campaigns
+		WHERE id = $1
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCampaignNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (r *PostgresRepository) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]models.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumnList + `
+		FROM campaigns
+		WHERE 1 = 1`
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.Name != "" {
+		args = append(args, "%"+escapeLike(filter.Name)+"%")
+		query += fmt.Sprintf(" AND name ILIKE $%d", len(args))
+	}
+	query += " ORDER BY id"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	return r.queryCampaigns(ctx, query, args...)
+}
+
+func (r *PostgresRepository) CreateCampaign(ctx context.Context, campaign models.Campaign) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO campaigns (id, name, image_url, cta, status)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (id) DO UPDATE
-		SET name = $2, image_url = $3, cta = $4, status = $5
-	`, campaign.ID, campaign.Name, campaign.ImageURL, campaign.CTA, campaign.Status)
+		INSERT INTO campaigns (`+campaignColumnList+`)
+		VALUES (`+campaignPlaceholders+`)
+	`, campaignArgs(campaign)...)
+	if isPostgresError(err, uniqueViolation) {
+		return ErrCampaignExists
+	}
+	return err
+}
+
+func (r *PostgresRepository) UpdateCampaign(ctx context.Context, campaign models.Campaign) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE campaigns
+		SET `+campaignAssignments+`
+		WHERE id = $1
+	`, campaignArgs(campaign)...)
+	return affectedOrNotFound(result, err, ErrCampaignNotFound)
+}
+
+func (r *PostgresRepository) UpdateCampaignStatus(ctx context.Context, id string, status models.Status) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE campaigns SET status = $2 WHERE id = $1`, id, status)
+	return affectedOrNotFound(result, err, ErrCampaignNotFound)
+}
+
+func (r *PostgresRepository) DeleteCampaign(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM campaigns WHERE id = $1`, id)
+	return affectedOrNotFound(result, err, ErrCampaignNotFound)
+}
+
+func (r *PostgresRepository) SaveCampaign(ctx context.Context, campaign models.Campaign) error {
+	_, err := r.db.ExecContext(ctx, upsertCampaignQuery, campaignArgs(campaign)...)
 	return err
 }
 
 func (r *PostgresRepository) GetTargetingRules(ctx context.Context) ([]models.TargetingRule, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT campaign_id, dimension_type, rule_type, values
+		SELECT campaign_id, dimension_type, rule_type, match_type, values
 		FROM targeting_rules
 	`)
 	if err != nil {
@@ -106,7 +204,7 @@ func (r *PostgresRepository) GetTargetingRules(ctx context.Context) ([]models.Ta
 	var rules []models.TargetingRule
 	for rows.Next() {
 		var r models.TargetingRule
-		if err := rows.Scan(&r.CampaignID, &r.DimensionType, &r.RuleType, &r.Values); err != nil {
+		if err := rows.Scan(&r.CampaignID, &r.DimensionType, &r.RuleType, &r.MatchType, &r.Values); err != nil {
 			return nil, err
 		}
 		rules = append(rules, r)
@@ -115,103 +213,405 @@ func (r *PostgresRepository) GetTargetingRules(ctx context.Context) ([]models.Ta
 	return rules, rows.Err()
 }
 
-func (r *PostgresRepository) SaveTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+func (r *PostgresRepository) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]models.TargetingRule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT campaign_id, dimension_type, rule_type, match_type, values
+		FROM targeting_rules
+		WHERE campaign_id = $1
+	`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.TargetingRule
+	for rows.Next() {
+		var r models.TargetingRule
+		if err := rows.Scan(&r.CampaignID, &r.DimensionType, &r.RuleType, &r.MatchType, &r.Values); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *PostgresRepository) CreateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO targeting_rules (campaign_id, dimension_type, rule_type, values)
-		VALUES ($1, $2, $3, $4)
-	`, rule.CampaignID, rule.DimensionType, rule.RuleType, rule.Values)
+		INSERT INTO targeting_rules (campaign_id, dimension_type, rule_type, match_type, values)
+		VALUES ($1, $2, $3, $4, $5)
+	`, rule.CampaignID, rule.DimensionType, rule.RuleType, rule.MatchType.OrDefault(), rule.Values)
+	switch {
+	case isPostgresError(err, uniqueViolation):
+		return ErrRuleExists
+	case isPostgresError(err, foreignKeyViolation):
+		return ErrCampaignNotFound
+	}
 	return err
 }
 
-func (r *PostgresRepository) Close(ctx context.Context) error {
-	return r.db.Close()
+func (r *PostgresRepository) UpdateTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE targeting_rules
+		SET rule_type = $3, match_type = $4, values = $5
+		WHERE campaign_id = $1 AND dimension_type = $2
+	`, rule.CampaignID, rule.DimensionType, rule.RuleType, rule.MatchType.OrDefault(), rule.Values)
+	return affectedOrNotFound(result, err, ErrRuleNotFound)
 }
 
-func (r *PostgresRepository) InitTestData(ctx context.Context) error {
-	spotifyAd := models.Campaign{
-		ID:       "spotify",
-		Name:     "Spotify - Music for everyone",
-		ImageURL: "https://somelink",
-		CTA:      "Download",
-		Status:   models.StatusActive,
+func (r *PostgresRepository) DeleteTargetingRule(ctx context.Context, campaignID string, dimension models.DimensionType) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM targeting_rules
+		WHERE campaign_id = $1 AND dimension_type = $2
+	`, campaignID, dimension)
+	return affectedOrNotFound(result, err, ErrRuleNotFound)
+}
+
+func (r *PostgresRepository) SaveTargetingRule(ctx context.Context, rule models.TargetingRule) error {
+	_, err := r.db.ExecContext(ctx, upsertRuleQuery, rule.CampaignID, rule.DimensionType, rule.RuleType, rule.MatchType.OrDefault(), rule.Values)
+	return err
+}
+
+func (r *PostgresRepository) ApplyBatch(ctx context.Context, batch Batch) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	duolingoAd := models.Campaign{
-		ID:       "duolingo",
-		Name:     "Duolingo: Best way to learn",
-		ImageURL: "https://somelink2",
-		CTA:      "Install",
-		Status:   models.StatusActive,
+	for _, campaign := range batch.SaveCampaigns {
+		_, err := tx.ExecContext(ctx, upsertCampaignQuery, campaignArgs(campaign)...)
+		if err != nil {
+			return fmt.Errorf("saving campaign %s: %w", campaign.ID, err)
+		}
+	}
+	for _, key := range batch.DeleteRules {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM targeting_rules WHERE campaign_id = $1 AND dimension_type = $2
+		`, key.CampaignID, key.Dimension)
+		if err != nil {
+			return fmt.Errorf("deleting %s rule of campaign %s: %w", key.Dimension, key.CampaignID, err)
+		}
+	}
+	for _, rule := range batch.SaveRules {
+		_, err := tx.ExecContext(ctx, upsertRuleQuery, rule.CampaignID, rule.DimensionType, rule.RuleType, rule.MatchType.OrDefault(), rule.Values)
+		if isPostgresError(err, foreignKeyViolation) {
+			return fmt.Errorf("saving %s rule of campaign %s: %w", rule.DimensionType, rule.CampaignID, ErrCampaignNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("saving %s rule of campaign %s: %w", rule.DimensionType, rule.CampaignID, err)
+		}
 	}
+	for _, expr := range batch.SaveExpressions {
+		_, err := tx.ExecContext(ctx, upsertExpressionQuery, expr.CampaignID, expr.Expression)
+		if isPostgresError(err, foreignKeyViolation) {
+			return fmt.Errorf("saving expression of campaign %s: %w", expr.CampaignID, ErrCampaignNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("saving expression of campaign %s: %w", expr.CampaignID, err)
+		}
+	}
+	for _, c := range batch.SaveCreatives {
+		_, err := tx.ExecContext(ctx, upsertCreativeQuery, creativeArgs(c)...)
+		if isPostgresError(err, foreignKeyViolation) {
+			return fmt.Errorf("saving creative %s of campaign %s: %w", c.ID, c.CampaignID, ErrCampaignNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("saving creative %s of campaign %s: %w", c.ID, c.CampaignID, err)
+		}
+	}
+	if len(batch.DeleteCampaigns) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM campaigns WHERE id = ANY($1)
+		`, pq.StringArray(batch.DeleteCampaigns))
+		if err != nil {
+			return fmt.Errorf("deleting campaigns: %w", err)
+		}
+	}
+	return tx.Commit()
+}
 
-	subwaySurferAd := models.Campaign{
-		ID:       "subwaysurfer",
-		Name:     "Subway Surfer",
-		ImageURL: "https://somelink3",
-		CTA:      "Play",
-		Status:   models.StatusActive,
+func (r *PostgresRepository) GetTargetingExpressions(ctx context.Context) ([]models.TargetingExpression, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT campaign_id, expression
+		FROM targeting_expressions
+	`)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if err := r.SaveCampaign(ctx, spotifyAd); err != nil {
-		return err
+	var exprs []models.TargetingExpression
+	for rows.Next() {
+		var e models.TargetingExpression
+		if err := rows.Scan(&e.CampaignID, &e.Expression); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
 	}
-	if err := r.SaveCampaign(ctx, duolingoAd); err != nil {
-		return err
+
+	return exprs, rows.Err()
+}
+
+func (r *PostgresRepository) GetTargetingExpressionByCampaignID(ctx context.Context, campaignID string) (*models.TargetingExpression, error) {
+	var e models.TargetingExpression
+	err := r.db.QueryRowContext(ctx, `
+		SELECT campaign_id, expression
+		FROM targeting_expressions
+		WHERE campaign_id = $1
+	`, campaignID).Scan(&e.CampaignID, &e.Expression)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExpressionNotFound
 	}
-	if err := r.SaveCampaign(ctx, subwaySurferAd); err != nil {
-		return err
+	if err != nil {
+		return nil, err
 	}
 
-	spotifyCountryRule := models.TargetingRule{
-		CampaignID:    "spotify",
-		DimensionType: models.DimensionCountry,
-		RuleType:      models.Include,
-		Values:        []string{"US", "Canada"},
+	return &e, nil
+}
+
+const upsertExpressionQuery = `
+	INSERT INTO targeting_expressions (campaign_id, expression)
+	VALUES ($1, $2)
+	ON CONFLICT (campaign_id) DO UPDATE
+	SET expression = $2
+`
+
+func (r *PostgresRepository) SaveTargetingExpression(ctx context.Context, expr models.TargetingExpression) error {
+	_, err := r.db.ExecContext(ctx, upsertExpressionQuery, expr.CampaignID, expr.Expression)
+	if isPostgresError(err, foreignKeyViolation) {
+		return ErrCampaignNotFound
 	}
+	return err
+}
+
+func (r *PostgresRepository) DeleteTargetingExpression(ctx context.Context, campaignID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM targeting_expressions WHERE campaign_id = $1`, campaignID)
+	return affectedOrNotFound(result, err, ErrExpressionNotFound)
+}
 
-	duolingoOSRule := models.TargetingRule{
-		CampaignID:    "duolingo",
-		DimensionType: models.DimensionOS,
-		RuleType:      models.Include,
-		Values:        []string{"Android", "iOS"},
+const creativeColumnList = "campaign_id, id, type, url, html, width, height, cta, locale, weight"
+
+func (r *PostgresRepository) queryCreatives(ctx context.Context, query string, args ...interface{}) ([]models.Creative, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	duolingoCountryRule := models.TargetingRule{
-		CampaignID:    "duolingo",
-		DimensionType: models.DimensionCountry,
-		RuleType:      models.Exclude,
-		Values:        []string{"US"},
+	var creatives []models.Creative
+	for rows.Next() {
+		var c models.Creative
+		err := rows.Scan(&c.CampaignID, &c.ID, &c.Type, &c.URL, &c.HTML, &c.Width, &c.Height, &c.CTA, &c.Locale, &c.Weight)
+		if err != nil {
+			return nil, err
+		}
+		creatives = append(creatives, c)
 	}
 
-	subwaysurferOSRule := models.TargetingRule{
-		CampaignID:    "subwaysurfer",
-		DimensionType: models.DimensionOS,
-		RuleType:      models.Include,
-		Values:        []string{"Android"},
+	return creatives, rows.Err()
+}
+
+func (r *PostgresRepository) GetCreatives(ctx context.Context) ([]models.Creative, error) {
+	return r.queryCreatives(ctx, `
+		SELECT `+creativeColumnList+`
+		FROM creatives
+		ORDER BY campaign_id, id
+	`)
+}
+
+func (r *PostgresRepository) GetCreativesByCampaignID(ctx context.Context, campaignID string) ([]models.Creative, error) {
+	return r.queryCreatives(ctx, `
+		SELECT `+creativeColumnList+`
+		FROM creatives
+		WHERE campaign_id = $1
+		ORDER BY id
+	`, campaignID)
+}
+
+func (r *PostgresRepository) CreateCreative(ctx context.Context, c models.Creative) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO creatives (`+creativeColumnList+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, c.CampaignID, c.ID, c.Type, c.URL, c.HTML, c.Width, c.Height, c.CTA, c.Locale, c.Weight)
+	switch {
+	case isPostgresError(err, uniqueViolation):
+		return ErrCreativeExists
+	case isPostgresError(err, foreignKeyViolation):
+		return ErrCampaignNotFound
 	}
+	return err
+}
+
+func (r *PostgresRepository) UpdateCreative(ctx context.Context, c models.Creative) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE creatives
+		SET type = $3, url = $4, html = $5, width = $6, height = $7, cta = $8, locale = $9, weight = $10
+		WHERE campaign_id = $1 AND id = $2
+	`, c.CampaignID, c.ID, c.Type, c.URL, c.HTML, c.Width, c.Height, c.CTA, c.Locale, c.Weight)
+	return affectedOrNotFound(result, err, ErrCreativeNotFound)
+}
+
+func (r *PostgresRepository) DeleteCreative(ctx context.Context, campaignID, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM creatives WHERE campaign_id = $1 AND id = $2`, campaignID, id)
+	return affectedOrNotFound(result, err, ErrCreativeNotFound)
+}
+
+// SaveCreative creates or replaces a creative.
+const upsertCreativeQuery = `
+	INSERT INTO creatives (` + creativeColumnList + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (campaign_id, id) DO UPDATE
+	SET type = $3, url = $4, html = $5, width = $6, height = $7, cta = $8, locale = $9, weight = $10
+`
+
+func creativeArgs(c models.Creative) []interface{} {
+	return []interface{}{c.CampaignID, c.ID, c.Type, c.URL, c.HTML, c.Width, c.Height, c.CTA, c.Locale, c.Weight}
+}
 
-	subwaysurferAppRule := models.TargetingRule{
-		CampaignID:    "subwaysurfer",
-		DimensionType: models.DimensionApp,
-		RuleType:      models.Include,
-		Values:        []string{"com.gametion.ludokinggame"},
+func (r *PostgresRepository) SaveCreative(ctx context.Context, c models.Creative) error {
+	_, err := r.db.ExecContext(ctx, upsertCreativeQuery, creativeArgs(c)...)
+	if isPostgresError(err, foreignKeyViolation) {
+		return ErrCampaignNotFound
 	}
+	return err
+}
 
-	if err := r.SaveTargetingRule(ctx, spotifyCountryRule); err != nil {
+// PostgreSQL error codes the repository translates into its own errors.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+func isPostgresError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
+// affectedOrNotFound turns an UPDATE or DELETE that matched no row into notFound.
+func affectedOrNotFound(result sql.Result, err error, notFound error) error {
+	if err != nil {
 		return err
 	}
-	if err := r.SaveTargetingRule(ctx, duolingoOSRule); err != nil {
+	affected, err := result.RowsAffected()
+	if err != nil {
 		return err
 	}
-	if err := r.SaveTargetingRule(ctx, duolingoCountryRule); err != nil {
-		return err
+	if affected == 0 {
+		return notFound
 	}
-	if err := r.SaveTargetingRule(ctx, subwaysurferOSRule); err != nil {
-		return err
+	return nil
+}
+
+// placeholders returns "$from, $from+1, ..." for n parameters.
+func placeholders(from, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", from+i)
 	}
-	if err := r.SaveTargetingRule(ctx, subwaysurferAppRule); err != nil {
-		return err
+	return strings.Join(params, ", ")
+}
+
+// assignments returns "a = $from, b = $from+1, ..." for an UPDATE's SET clause.
+func assignments(columns []string, from int) string {
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = fmt.Sprintf("%s = $%d", column, from+i)
 	}
+	return strings.Join(sets, ", ")
+}
 
-	return nil
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *PostgresRepository) Close(ctx context.Context) error {
+	return r.db.Close()
+}
+
+// Listen subscribes to the change notifications published by the campaign and
+// rule triggers. It opens a dedicated connection that reconnects on its own;
+// a ChangeResync event is sent once the subscription is (re)established. The
+// events channel is closed when ctx is done or the subscription fails.
+func (r *PostgresRepository) Listen(ctx context.Context) (<-chan ChangeEvent, error) {
+	events := make(chan ChangeEvent, 64)
+	send := func(ev ChangeEvent) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	}
+
+	// The callback runs on the listener's connection loop, so it must not
+	// block: it only flags the disconnect for the loop below to report.
+	var disconnected atomic.Bool
+	wake := make(chan struct{}, 1)
+	listener := pq.NewListener(r.uri, 100*time.Millisecond, 10*time.Second, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("PostgreSQL change listener is disconnected", "error", err)
+			disconnected.Store(true)
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		case pq.ListenerEventReconnected:
+			slog.Info("PostgreSQL change listener reconnected")
+		}
+	})
+
+	subscribed := make(chan error, 1)
+	go func() {
+		// Listen blocks until a connection is available.
+		subscribed <- listener.Listen(changeChannel)
+	}()
+
+	go func() {
+		defer close(events)
+		defer listener.Close()
+
+		// A periodic ping notices dead connections the server never closed.
+		ping := time.NewTicker(time.Minute)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-subscribed:
+				if err != nil {
+					if ctx.Err() == nil {
+						slog.Error("Couldn't listen for targeting changes", "error", err)
+					}
+					return
+				}
+				send(ChangeEvent{Kind: ChangeResync})
+			case <-wake:
+				if disconnected.Swap(false) {
+					send(ChangeEvent{Kind: ChangeDisconnected})
+				}
+			case <-ping.C:
+				go listener.Ping()
+			case n := <-listener.Notify:
+				// A nil notification is sent after reconnecting.
+				if n == nil {
+					send(ChangeEvent{Kind: ChangeResync})
+					continue
+				}
+				ev := ChangeEvent{Kind: ChangeRow}
+				if err := json.Unmarshal([]byte(n.Extra), &ev); err != nil {
+					slog.Warn("Ignoring malformed targeting change", "payload", n.Extra, "error", err)
+					send(ChangeEvent{Kind: ChangeResync})
+					continue
+				}
+				send(ev)
+			}
+		}
+	}()
+
+	return events, nil
 }
@@ -2,75 +2,124 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"errors"
+
+	"targeting-engine/internal/fixtures"
 	"targeting-engine/internal/models"
 )
 
+var (
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrCampaignExists   = errors.New("campaign already exists")
+	ErrRuleNotFound     = errors.New("targeting rule not found")
+	ErrRuleExists       = errors.New("targeting rule already exists for this dimension")
+
+	ErrExpressionNotFound = errors.New("targeting expression not found")
+	ErrCreativeNotFound   = errors.New("creative not found")
+	ErrCreativeExists     = errors.New("creative already exists")
+
+	// ErrReadOnly is returned by the writes of repositories that can't be
+	// changed through the API, such as FileRepository.
+	ErrReadOnly = errors.New("campaigns are read-only, change the campaigns file instead")
+)
+
 type Repository interface {
 	GetCampaigns(ctx context.Context) ([]models.Campaign, error)
+	GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error)
+	ListCampaigns(ctx context.Context, filter CampaignFilter) ([]models.Campaign, error)
+	CreateCampaign(ctx context.Context, campaign models.Campaign) error
+	UpdateCampaign(ctx context.Context, campaign models.Campaign) error
+	UpdateCampaignStatus(ctx context.Context, id string, status models.Status) error
+	DeleteCampaign(ctx context.Context, id string) error
+
 	GetTargetingRules(ctx context.Context) ([]models.TargetingRule, error)
+	GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]models.TargetingRule, error)
+	CreateTargetingRule(ctx context.Context, rule models.TargetingRule) error
+	UpdateTargetingRule(ctx context.Context, rule models.TargetingRule) error
+	DeleteTargetingRule(ctx context.Context, campaignID string, dimension models.DimensionType) error
+
+	GetTargetingExpressions(ctx context.Context) ([]models.TargetingExpression, error)
+	GetTargetingExpressionByCampaignID(ctx context.Context, campaignID string) (*models.TargetingExpression, error)
+	SaveTargetingExpression(ctx context.Context, expr models.TargetingExpression) error
+	DeleteTargetingExpression(ctx context.Context, campaignID string) error
+
+	GetCreatives(ctx context.Context) ([]models.Creative, error)
+	GetCreativesByCampaignID(ctx context.Context, campaignID string) ([]models.Creative, error)
+	CreateCreative(ctx context.Context, creative models.Creative) error
+	UpdateCreative(ctx context.Context, creative models.Creative) error
+	DeleteCreative(ctx context.Context, campaignID, id string) error
+
+	// ApplyBatch makes all of a batch's writes or, on error, none of them.
+	ApplyBatch(ctx context.Context, batch Batch) error
+
+	// Ping checks that the repository's backing store is reachable.
+	Ping(ctx context.Context) error
 	Close(ctx context.Context) error
 }
 
-type repository struct {
-	db *sql.DB
+// CampaignFilter narrows down ListCampaigns. Zero values match everything.
+type CampaignFilter struct {
+	Status models.Status
+	// Name matches campaigns whose name contains it, ignoring case.
+	Name   string
+	Limit  int
+	Offset int
 }
 
-func NewRepository(db *sql.DB) Repository {
-	return &repository{db: db}
+// Batch is a set of campaign, rule, expression and creative writes applied
+// together. Deleting a campaign deletes its rules, expression and creatives
+// too.
+type Batch struct {
+	SaveCampaigns   []models.Campaign
+	DeleteCampaigns []string
+	SaveRules       []models.TargetingRule
+	DeleteRules     []RuleKey
+	SaveExpressions []models.TargetingExpression
+	SaveCreatives   []models.Creative
 }
 
-func (r *repository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
-	query := `
-		SELECT id, name, image_url, cta, status
-		FROM campaigns
-		WHERE status = 'ACTIVE'
-	`
-
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var campaigns []models.Campaign
-	for rows.Next() {
-		var campaign models.Campaign
-		err := rows.Scan(&campaign.ID, &campaign.Name, &campaign.ImageURL, &campaign.CTA, &campaign.Status)
-		if err != nil {
-			return nil, err
-		}
-		campaigns = append(campaigns, campaign)
+// FixturesBatch returns a batch that upserts the fixtures, so applying it
+// again changes nothing. Other campaigns, and rules the fixtures don't
+// mention, are kept.
+func FixturesBatch(f *fixtures.Fixtures) Batch {
+	return Batch{
+		SaveCampaigns:   f.Campaigns,
+		SaveRules:       f.Rules,
+		SaveExpressions: f.Expressions,
+		SaveCreatives:   f.Creatives,
 	}
+}
 
-	return campaigns, nil
+// RuleKey identifies a targeting rule; a campaign has one rule per dimension.
+type RuleKey struct {
+	CampaignID string
+	Dimension  models.DimensionType
 }
 
-func (r *repository) GetTargetingRules(ctx context.Context) ([]models.TargetingRule, error) {
-	query := `
-		SELECT campaign_id, dimension_type, rule_type, values
-		FROM targeting_rules
-	`
+type ChangeKind string
 
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var rules []models.TargetingRule
-	for rows.Next() {
-		var rule models.TargetingRule
-		err := rows.Scan(&rule.CampaignID, &rule.DimensionType, &rule.RuleType, &rule.Values)
-		if err != nil {
-			return nil, err
-		}
-		rules = append(rules, rule)
-	}
+const (
+	// ChangeRow reports a write to a campaign or one of its targeting rules.
+	ChangeRow ChangeKind = "ROW"
+	// ChangeResync means notifications may have been missed, e.g. after the
+	// listener (re)connected, and everything has to be reloaded.
+	ChangeResync ChangeKind = "RESYNC"
+	// ChangeDisconnected means the listener lost its connection and no
+	// notifications will arrive until the next ChangeResync.
+	ChangeDisconnected ChangeKind = "DISCONNECTED"
+)
 
-	return rules, nil
+type ChangeEvent struct {
+	Kind       ChangeKind `json:"-"`
+	Table      string     `json:"table"`
+	Op         string     `json:"op"`
+	CampaignID string     `json:"campaign_id"`
 }
 
-func (r *repository) Close(ctx context.Context) error {
-	return r.db.Close()
+// Notifier is implemented by repositories that can push change events to the
+// targeting service instead of being polled.
+type Notifier interface {
+	// Listen streams change events until ctx is done. The channel is
+	// closed if the notifier gives up, after which no events arrive.
+	Listen(ctx context.Context) (<-chan ChangeEvent, error)
 }
@@ -0,0 +1,21 @@
+// Package seed holds the demo campaigns used for local development and
+// tests.
+package seed
+
+import (
+	_ "embed"
+
+	"targeting-engine/internal/fixtures"
+)
+
+//go:embed demo.yaml
+var demo []byte
+
+// Demo returns the demo campaigns used for local development and tests.
+func Demo() *fixtures.Fixtures {
+	f, err := fixtures.Parse(demo)
+	if err != nil {
+		panic("seed: invalid demo fixtures: " + err.Error())
+	}
+	return f
+}
@@ -0,0 +1,10 @@
+package seed
+
+import "testing"
+
+func TestDemo(t *testing.T) {
+	f := Demo()
+	if len(f.Campaigns) != 3 || len(f.Rules) != 5 {
+		t.Errorf("Expected 3 demo campaigns and 5 rules but got %d and %d", len(f.Campaigns), len(f.Rules))
+	}
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"targeting-engine/internal/bulk"
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+// CampaignService validates and stores the campaign and targeting rule
+// changes made through the admin API. Running targeting services pick the
+// changes up through the repository's change notifications.
+type CampaignService struct {
+	repo repository.Repository
+}
+
+func NewCampaignService(repo repository.Repository) *CampaignService {
+	return &CampaignService{
+		repo: repo,
+	}
+}
+
+func (s *CampaignService) CreateCampaign(ctx context.Context, campaign models.Campaign) (*models.Campaign, error) {
+	setDefaults(&campaign)
+	if err := campaign.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// setDefaults fills in what clients may leave out of a new campaign.
+func setDefaults(campaign *models.Campaign) {
+	if campaign.Status == "" {
+		campaign.Status = models.StatusActive
+	}
+}
+
+func (s *CampaignService) GetCampaign(ctx context.Context, id string) (*models.Campaign, error) {
+	return s.repo.GetCampaignByID(ctx, id)
+}
+
+func (s *CampaignService) ListCampaigns(ctx context.Context, filter repository.CampaignFilter) ([]models.Campaign, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, &models.ValidationError{Field: "status", Message: "unknown status " + string(filter.Status)}
+	}
+	return s.repo.ListCampaigns(ctx, filter)
+}
+
+func (s *CampaignService) UpdateCampaign(ctx context.Context, campaign models.Campaign) (*models.Campaign, error) {
+	if err := campaign.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (s *CampaignService) PatchCampaign(ctx context.Context, id string, patch models.CampaignPatch) (*models.Campaign, error) {
+	campaign, err := s.repo.GetCampaignByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	patch.Apply(campaign)
+	return s.UpdateCampaign(ctx, *campaign)
+}
+
+func (s *CampaignService) DeleteCampaign(ctx context.Context, id string) error {
+	return s.repo.DeleteCampaign(ctx, id)
+}
+
+func (s *CampaignService) ListRules(ctx context.Context, campaignID string) ([]models.TargetingRule, error) {
+	if _, err := s.repo.GetCampaignByID(ctx, campaignID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTargetingRulesByCampaignID(ctx, campaignID)
+}
+
+func (s *CampaignService) GetRule(ctx context.Context, campaignID string, dimension models.DimensionType) (*models.TargetingRule, error) {
+	rules, err := s.ListRules(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		if rule.DimensionType == dimension {
+			return &rule, nil
+		}
+	}
+	return nil, repository.ErrRuleNotFound
+}
+
+func (s *CampaignService) CreateRule(ctx context.Context, rule models.TargetingRule) (*models.TargetingRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateTargetingRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *CampaignService) UpdateRule(ctx context.Context, rule models.TargetingRule) (*models.TargetingRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateTargetingRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *CampaignService) DeleteRule(ctx context.Context, campaignID string, dimension models.DimensionType) error {
+	return s.repo.DeleteTargetingRule(ctx, campaignID, dimension)
+}
+
+func (s *CampaignService) GetExpression(ctx context.Context, campaignID string) (*models.TargetingExpression, error) {
+	return s.repo.GetTargetingExpressionByCampaignID(ctx, campaignID)
+}
+
+func (s *CampaignService) SetExpression(ctx context.Context, expr models.TargetingExpression) (*models.TargetingExpression, error) {
+	if err := expr.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveTargetingExpression(ctx, expr); err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+func (s *CampaignService) DeleteExpression(ctx context.Context, campaignID string) error {
+	return s.repo.DeleteTargetingExpression(ctx, campaignID)
+}
+
+func (s *CampaignService) ListCreatives(ctx context.Context, campaignID string) ([]models.Creative, error) {
+	if _, err := s.repo.GetCampaignByID(ctx, campaignID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCreativesByCampaignID(ctx, campaignID)
+}
+
+func (s *CampaignService) GetCreative(ctx context.Context, campaignID, id string) (*models.Creative, error) {
+	creatives, err := s.ListCreatives(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, creative := range creatives {
+		if creative.ID == id {
+			return &creative, nil
+		}
+	}
+	return nil, repository.ErrCreativeNotFound
+}
+
+func (s *CampaignService) CreateCreative(ctx context.Context, creative models.Creative) (*models.Creative, error) {
+	if err := creative.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCreative(ctx, creative); err != nil {
+		return nil, err
+	}
+	return &creative, nil
+}
+
+func (s *CampaignService) UpdateCreative(ctx context.Context, creative models.Creative) (*models.Creative, error) {
+	if err := creative.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateCreative(ctx, creative); err != nil {
+		return nil, err
+	}
+	return &creative, nil
+}
+
+func (s *CampaignService) DeleteCreative(ctx context.Context, campaignID, id string) error {
+	return s.repo.DeleteCreative(ctx, campaignID, id)
+}
+
+// ImportOptions control how Import applies a set of campaigns.
+type ImportOptions struct {
+	// DryRun plans the import without writing anything.
+	DryRun bool
+	// Prune deletes the campaigns that aren't in the import.
+	Prune bool
+}
+
+// Export returns every campaign with its targeting rules, sorted by ID.
+func (s *CampaignService) Export(ctx context.Context) (*bulk.Data, error) {
+	campaigns, err := s.repo.GetCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.repo.GetTargetingRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copied, so sorting leaves the repository's slices alone.
+	data := &bulk.Data{
+		Campaigns: append([]models.Campaign{}, campaigns...),
+		Rules:     append([]models.TargetingRule{}, rules...),
+	}
+	data.Sort()
+	return data, nil
+}
+
+// Import makes the stored campaigns and rules match data, as planned by
+// bulk.Diff, in one all-or-nothing batch. It returns the plan, which is
+// all it does on a dry run. Campaigns without a status are made active, as
+// CreateCampaign does.
+func (s *CampaignService) Import(ctx context.Context, data *bulk.Data, opts ImportOptions) (*bulk.Plan, error) {
+	for i := range data.Campaigns {
+		setDefaults(&data.Campaigns[i])
+	}
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	current, err := s.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := bulk.Diff(current, data, opts.Prune)
+	if err != nil {
+		return nil, err
+	}
+	plan.DryRun = opts.DryRun
+	if err := s.checkPrune(ctx, plan.Batch().DeleteCampaigns); err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun || len(plan.Changes) == 0 {
+		return plan, nil
+	}
+	if err := s.repo.ApplyBatch(ctx, plan.Batch()); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// checkPrune refuses to prune campaigns that have a targeting expression or
+// creatives. Bulk files carry neither, so deleting the campaign would lose
+// them for good.
+func (s *CampaignService) checkPrune(ctx context.Context, campaignIDs []string) error {
+	if len(campaignIDs) == 0 {
+		return nil
+	}
+	deleted := make(map[string]bool, len(campaignIDs))
+	for _, id := range campaignIDs {
+		deleted[id] = true
+	}
+
+	expressions, err := s.repo.GetTargetingExpressions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range expressions {
+		if deleted[e.CampaignID] {
+			return &models.ValidationError{Field: "prune", Message: fmt.Sprintf("campaign %s has a targeting expression, which imports don't carry; remove it first", e.CampaignID)}
+		}
+	}
+
+	creatives, err := s.repo.GetCreatives(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range creatives {
+		if deleted[c.CampaignID] {
+			return &models.ValidationError{Field: "prune", Message: fmt.Sprintf("campaign %s has creatives, which imports don't carry; remove them first", c.CampaignID)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+func TestCampaignServiceValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCampaignService(newTestRepository(t))
+
+	valid := models.Campaign{ID: "netflix", Name: "Netflix", ImageURL: "https://img", CTA: "Watch"}
+
+	tests := []struct {
+		name     string
+		campaign func(c models.Campaign) models.Campaign
+		field    string
+	}{
+		{name: "Missing id", campaign: func(c models.Campaign) models.Campaign { c.ID = ""; return c }, field: "id"},
+		{name: "Slash in id", campaign: func(c models.Campaign) models.Campaign { c.ID = "a/b"; return c }, field: "id"},
+		{name: "Missing name", campaign: func(c models.Campaign) models.Campaign { c.Name = " "; return c }, field: "name"},
+		{name: "Unknown status", campaign: func(c models.Campaign) models.Campaign { c.Status = "PAUSED"; return c }, field: "status"},
+		{name: "Experiment over 100%", campaign: func(c models.Campaign) models.Campaign {
+			c.Experiment = &models.Experiment{ID: "e", Arms: []models.Arm{{ID: "a", Traffic: 60}, {ID: "b", Traffic: 50}}}
+			return c
+		}, field: "experiment.arms"},
+		{name: "Invalid arm rule", campaign: func(c models.Campaign) models.Campaign {
+			c.Experiment = &models.Experiment{ID: "e", Arms: []models.Arm{{ID: "a", Traffic: 50, Rules: []models.TargetingRule{{DimensionType: "CITY"}}}}}
+			return c
+		}, field: "experiment.arms[0].rules[0].dimension_type"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := svc.CreateCampaign(ctx, tc.campaign(valid))
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != tc.field {
+				t.Errorf("Expected a validation error on %s but got %v", tc.field, err)
+			}
+		})
+	}
+
+	created, err := svc.CreateCampaign(ctx, valid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created.Status != models.StatusActive {
+		t.Errorf("Expected new campaigns to default to %s but got %s", models.StatusActive, created.Status)
+	}
+	if _, err := svc.CreateCampaign(ctx, valid); !errors.Is(err, repository.ErrCampaignExists) {
+		t.Errorf("Expected ErrCampaignExists but got %v", err)
+	}
+}
+
+func TestCampaignServiceRules(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCampaignService(newTestRepository(t))
+
+	rule := models.TargetingRule{CampaignID: "spotify", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"iOS"}}
+	if _, err := svc.CreateRule(ctx, rule); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := svc.CreateRule(ctx, rule); !errors.Is(err, repository.ErrRuleExists) {
+		t.Errorf("Expected ErrRuleExists but got %v", err)
+	}
+
+	bad := rule
+	bad.DimensionType = "DEVICE"
+	if _, err := svc.CreateRule(ctx, bad); err == nil {
+		t.Error("Expected an unknown dimension to be rejected")
+	}
+	bad = rule
+	bad.RuleType = "MAYBE"
+	if _, err := svc.CreateRule(ctx, bad); err == nil {
+		t.Error("Expected an unknown rule type to be rejected")
+	}
+
+	missing := rule
+	missing.CampaignID = "missing"
+	if _, err := svc.CreateRule(ctx, missing); !errors.Is(err, repository.ErrCampaignNotFound) {
+		t.Errorf("Expected ErrCampaignNotFound but got %v", err)
+	}
+
+	paused := models.StatusInactive
+	campaign, err := svc.PatchCampaign(ctx, "spotify", models.CampaignPatch{Status: &paused})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if campaign.Status != models.StatusInactive || campaign.ID != "spotify" {
+		t.Errorf("Expected spotify to be paused but got %+v", campaign)
+	}
+
+	if err := svc.DeleteRule(ctx, "spotify", models.DimensionOS); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := svc.GetRule(ctx, "spotify", models.DimensionOS); !errors.Is(err, repository.ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound but got %v", err)
+	}
+}
+
+func TestCampaignServicePatch(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCampaignService(newTestRepository(t))
+
+	patch := func(body string) *models.Campaign {
+		t.Helper()
+		var p models.CampaignPatch
+		if err := json.Unmarshal([]byte(body), &p); err != nil {
+			t.Fatalf("Invalid patch %s: %v", body, err)
+		}
+		campaign, err := svc.PatchCampaign(ctx, "spotify", p)
+		if err != nil {
+			t.Fatalf("Patch %s failed: %v", body, err)
+		}
+		return campaign
+	}
+
+	campaign := patch(`{"start_at": "2024-06-01T00:00:00Z", "end_at": "2024-07-01T00:00:00Z"}`)
+	if campaign.StartAt == nil || campaign.EndAt == nil {
+		t.Fatalf("Expected the flight to be set but got %+v", campaign)
+	}
+	if campaign = patch(`{"name": "Spotify"}`); campaign.StartAt == nil || campaign.EndAt == nil {
+		t.Errorf("Expected missing fields to be left alone but got %+v", campaign)
+	}
+	if campaign = patch(`{"end_at": null}`); campaign.StartAt == nil || campaign.EndAt != nil {
+		t.Errorf("Expected null to clear only the end of the flight but got %+v", campaign)
+	}
+
+	campaign = patch(`{"experiment": {"id": "geo", "arms": [{"id": "control", "traffic": 50}]}}`)
+	if campaign.Experiment == nil || campaign.Experiment.ID != "geo" {
+		t.Fatalf("Expected the experiment to be set but got %+v", campaign.Experiment)
+	}
+	if campaign = patch(`{"experiment": null}`); campaign.Experiment != nil {
+		t.Errorf("Expected null to end the experiment but got %+v", campaign.Experiment)
+	}
+}
+
+func TestCampaignServiceImport(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	svc := NewCampaignService(repo)
+
+	data, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data.Campaigns[0].Name = "Renamed"
+	data.Rules = data.Rules[1:]
+
+	plan, err := svc.Import(ctx, data, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if plan.Summary.Updated != 1 || plan.Summary.Deleted != 1 {
+		t.Errorf("Expected 1 update and 1 delete but got %+v", plan.Summary)
+	}
+	if c, _ := repo.GetCampaignByID(ctx, data.Campaigns[0].ID); c.Name == "Renamed" {
+		t.Error("Expected a dry run to change nothing")
+	}
+
+	if _, err := svc.Import(ctx, data, ImportOptions{}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	after, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if after.Campaigns[0].Name != "Renamed" || len(after.Rules) != len(data.Rules) {
+		t.Errorf("Expected the import to be applied but got %+v", after)
+	}
+
+	// A missing status defaults to ACTIVE, as it does for CreateCampaign.
+	data.Campaigns = append(data.Campaigns, models.Campaign{ID: "new", Name: "New", ImageURL: "https://new", CTA: "Go"})
+	if _, err := svc.Import(ctx, data, ImportOptions{}); err != nil {
+		t.Fatalf("Import without a status failed: %v", err)
+	}
+	if c, err := repo.GetCampaignByID(ctx, "new"); err != nil || c.Status != models.StatusActive {
+		t.Errorf("Expected the imported campaign to be active but got %+v, %v", c, err)
+	}
+
+	data.Rules = append(data.Rules, models.TargetingRule{CampaignID: "unknown", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"iOS"}})
+	var validationErr *models.ValidationError
+	if _, err := svc.Import(ctx, data, ImportOptions{}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error for a rule of an unknown campaign but got %v", err)
+	}
+
+	// Imports don't carry expressions or creatives, so pruning a campaign with
+	// either would lose them.
+	expr := models.TargetingExpression{CampaignID: "paused", Expression: models.Expression{Dimension: models.DimensionOS, Values: []string{"iOS"}}}
+	if err := repo.SaveTargetingExpression(ctx, expr); err != nil {
+		t.Fatalf("Failed to save expression: %v", err)
+	}
+	kept, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	for i, c := range kept.Campaigns {
+		if c.ID == "paused" {
+			kept.Campaigns = append(kept.Campaigns[:i], kept.Campaigns[i+1:]...)
+			break
+		}
+	}
+	prune := ImportOptions{Prune: true, DryRun: true}
+	if _, err := svc.Import(ctx, kept, prune); !errors.As(err, &validationErr) || validationErr.Field != "prune" {
+		t.Errorf("Expected pruning a campaign with an expression to be refused but got %v", err)
+	}
+	if err := repo.DeleteTargetingExpression(ctx, "paused"); err != nil {
+		t.Fatalf("Failed to delete expression: %v", err)
+	}
+	if err := repo.SaveCreative(ctx, models.Creative{CampaignID: "paused", ID: "banner", Type: models.CreativeImage, URL: "https://banner"}); err != nil {
+		t.Fatalf("Failed to save creative: %v", err)
+	}
+	if _, err := svc.Import(ctx, kept, prune); !errors.As(err, &validationErr) || validationErr.Field != "prune" {
+		t.Errorf("Expected pruning a campaign with creatives to be refused but got %v", err)
+	}
+	if err := repo.DeleteCreative(ctx, "paused", "banner"); err != nil {
+		t.Fatalf("Failed to delete creative: %v", err)
+	}
+	if plan, err := svc.Import(ctx, kept, prune); err != nil || plan.Summary.Deleted != 1 {
+		t.Errorf("Expected paused to be pruned once its expression is gone but got %+v, %v", plan, err)
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"targeting-engine/internal/auction"
+	"targeting-engine/internal/models"
+)
+
+// WithAuction makes deliveries auction the eligible campaigns by their
+// BidCPM instead of only ranking them. Ranking still breaks ties between
+// equal bids.
+func WithAuction(a *auction.Auction) Option {
+	return func(s *TargetingService) {
+		s.auction.Store(a)
+	}
+}
+
+// SetAuction replaces the auction used by subsequent deliveries; nil turns
+// auctions off.
+func (s *TargetingService) SetAuction(a *auction.Auction) {
+	s.auction.Store(a)
+}
+
+// runAuction auctions the ranked campaigns when auctions are on. It returns
+// the winners, highest bid first, and the price each pays by campaign ID.
+// Without an auction campaigns are returned as they are, with no prices.
+func (s *TargetingService) runAuction(req models.DeliveryRequest, campaigns []*indexedCampaign) ([]*indexedCampaign, map[string]float64) {
+	a := s.auction.Load()
+	if a == nil {
+		return campaigns, nil
+	}
+
+	bids := make([]float64, len(campaigns))
+	for i, campaign := range campaigns {
+		bids[i] = campaign.BidCPM
+	}
+	winners := a.Run(req.App, req.Country, bids)
+
+	won := make([]*indexedCampaign, len(winners))
+	prices := make(map[string]float64, len(winners))
+	for i, w := range winners {
+		won[i] = campaigns[w.Bid]
+		prices[won[i].ID] = w.Price
+	}
+	return won, prices
+}
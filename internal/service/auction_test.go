@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"targeting-engine/internal/auction"
+	"targeting-engine/internal/budget"
+	"targeting-engine/internal/models"
+)
+
+func TestAuction(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "cheap", Status: models.StatusActive, BidCPM: 0.5},
+			{ID: "rich", Status: models.StatusActive, BidCPM: 4, LifetimeSpendBudget: 100},
+			{ID: "tied-b", Status: models.StatusActive, BidCPM: 2},
+			{ID: "tied-a", Status: models.StatusActive, BidCPM: 2},
+		},
+		nil,
+	)
+	a, err := auction.New(auction.SecondPrice, 0, []auction.Floor{{Country: "US", CPM: 1}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	budgets := budget.NewMemoryStore()
+	svc := NewTargetingService(repo, WithAuction(a), WithBudgetStore(budgets))
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", Limit: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(campaigns) != 2 || campaigns[0].CID != "rich" || campaigns[1].CID != "tied-a" {
+		t.Fatalf("Expected rich and then tied-a, which wins the tie by ID, but got %+v", campaigns)
+	}
+	// Second price: rich pays tied-a's bid and tied-a pays tied-b's, even
+	// though tied-b wasn't returned.
+	for i, want := range []float64{2, 2} {
+		if campaigns[i].Price == nil || *campaigns[i].Price != want {
+			t.Errorf("Expected %s to pay %v but got %v", campaigns[i].CID, want, campaigns[i].Price)
+		}
+	}
+
+	usage, _ := budgets.Usage(ctx, []budget.Key{{CampaignID: "rich", Day: "2024-06-01"}})
+	if spend := usage["rich"].LifetimeSpend; spend != 0.002 {
+		t.Errorf("Expected rich to be charged the clearing price but spent %v", spend)
+	}
+
+	// Without an auction nothing is priced and every campaign is returned.
+	svc.SetAuction(nil)
+	campaigns, _ = svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS"})
+	if len(campaigns) != 4 || campaigns[0].Price != nil {
+		t.Errorf("Expected all four campaigns without prices but got %+v", campaigns)
+	}
+}
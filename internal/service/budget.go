@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"targeting-engine/internal/budget"
+	"targeting-engine/internal/logging"
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+// WithBudgetStore sets where campaign budget usage is kept. The default is an
+// in-memory store local to this instance.
+func WithBudgetStore(store budget.Store) Option {
+	return func(s *TargetingService) {
+		s.budget = store
+	}
+}
+
+// budgetReservation is what reserveBudgets took from a campaign's budget.
+type budgetReservation struct {
+	key   budget.Key
+	spend float64
+	usage budget.Usage
+}
+
+// reserveBudgets drops the campaigns whose budgets are used up or whose pacing
+// holds them back at now, and reserves an impression at its bid for each of
+// the others, so concurrent requests can't overspend. The reservations must be
+// passed to settleBudgets once delivery is decided. Unlike frequency caps, a
+// failing store drops every budgeted campaign, since serving them could
+// overspend.
+func (s *TargetingService) reserveBudgets(ctx context.Context, campaigns []*indexedCampaign, now time.Time) ([]*indexedCampaign, map[string]budgetReservation) {
+	var reserved map[string]budgetReservation
+	allowed := campaigns[:0:0]
+	for _, campaign := range campaigns {
+		if !campaign.HasBudget() {
+			allowed = append(allowed, campaign)
+			continue
+		}
+
+		r := budgetReservation{
+			key:   budget.Key{CampaignID: campaign.ID, Day: budget.Day(now, campaign.location)},
+			spend: campaign.BidCPM / 1000,
+		}
+		eligible := func(usage budget.Usage) bool {
+			return budget.Eligible(&campaign.Campaign, usage, now, campaign.location)
+		}
+		var ok bool
+		var err error
+		r.usage, ok, err = s.budget.Reserve(ctx, r.key, 1, r.spend, eligible)
+		if err != nil {
+			logging.FromContext(ctx).Error("Couldn't reserve campaign budget, skipping campaign", "campaign", campaign.ID, "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if reserved == nil {
+			reserved = make(map[string]budgetReservation)
+		}
+		reserved[campaign.ID] = r
+		allowed = append(allowed, campaign)
+	}
+	return allowed, reserved
+}
+
+// settleBudgets charges the served campaigns their price, the auction's by
+// campaign ID in prices or else their bid, and gives back the reservations of
+// the campaigns that weren't served. Campaigns that use up their lifetime
+// budget are marked exhausted in the repository.
+func (s *TargetingService) settleBudgets(ctx context.Context, reserved map[string]budgetReservation, served []*indexedCampaign, prices map[string]float64) {
+	if len(reserved) == 0 {
+		return
+	}
+	// Settle even if the request is cancelled, or the reservations would
+	// count against the budgets for good.
+	ctx = context.WithoutCancel(ctx)
+
+	for _, campaign := range served {
+		r, ok := reserved[campaign.ID]
+		if !ok {
+			continue
+		}
+		delete(reserved, campaign.ID)
+
+		usage := r.usage
+		if price, ok := prices[campaign.ID]; ok && price/1000 != r.spend {
+			var err error
+			if usage, err = s.budget.Add(ctx, r.key, 0, price/1000-r.spend); err != nil {
+				logging.FromContext(ctx).Error("Couldn't charge campaign budget", "campaign", campaign.ID, "error", err)
+				continue
+			}
+		}
+
+		if budget.Exhausted(&campaign.Campaign, usage) {
+			// A read-only repository can't record the status; the budget
+			// keeps the campaign from serving all the same.
+			err := s.repo.UpdateCampaignStatus(ctx, campaign.ID, models.StatusExhausted)
+			if err != nil && !errors.Is(err, repository.ErrReadOnly) {
+				logging.FromContext(ctx).Error("Couldn't mark campaign exhausted", "campaign", campaign.ID, "error", err)
+			}
+		}
+	}
+
+	for id, r := range reserved {
+		if _, err := s.budget.Add(ctx, r.key, -1, -r.spend); err != nil {
+			logging.FromContext(ctx).Error("Couldn't release campaign budget", "campaign", id, "error", err)
+		}
+	}
+}
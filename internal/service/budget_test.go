@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/budget"
+	"targeting-engine/internal/models"
+)
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "daily", Status: models.StatusActive, DailyImpressionBudget: 2},
+			{ID: "lifetime", Status: models.StatusActive, BidCPM: 1000, LifetimeSpendBudget: 3},
+			{ID: "unlimited", Status: models.StatusActive},
+		},
+		nil,
+	)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewTargetingService(repo, WithClock(func() time.Time { return now }))
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	deliver := func() []string {
+		t.Helper()
+		campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var ids []string
+		for _, c := range campaigns {
+			ids = append(ids, c.CID)
+		}
+		return ids
+	}
+
+	for i := 0; i < 2; i++ {
+		if ids := deliver(); len(ids) != 3 {
+			t.Fatalf("Expected every campaign on delivery %d but got %v", i+1, ids)
+		}
+	}
+	if ids := deliver(); len(ids) != 2 || ids[0] != "lifetime" {
+		t.Fatalf("Expected the daily budget to hold but got %v", ids)
+	}
+	if c, _ := repo.GetCampaignByID(ctx, "lifetime"); c.Status != models.StatusExhausted {
+		t.Errorf("Expected the lifetime campaign to be marked exhausted but got %s", c.Status)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if ids := deliver(); len(ids) != 2 || ids[0] != "daily" {
+		t.Fatalf("Expected the daily budget to reset and the lifetime one to hold but got %v", ids)
+	}
+}
+
+func TestBudgetsConcurrentDeliveries(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "small", Status: models.StatusActive, BidCPM: 1000, LifetimeImpressionBudget: 5, Priority: 2},
+			{ID: "large", Status: models.StatusActive, BidCPM: 1000, LifetimeImpressionBudget: 100, Priority: 1},
+		},
+		nil,
+	)
+	budgets := budget.NewMemoryStore()
+	svc := NewTargetingService(repo, WithBudgetStore(budgets))
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	var small atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", Limit: 1})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			for _, c := range campaigns {
+				if c.CID == "small" {
+					small.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := small.Load(); n != 5 {
+		t.Errorf("Expected concurrent deliveries to stop at the budget of 5 but got %d", n)
+	}
+	// Only delivered impressions are left counted; the reservations of the
+	// campaigns cut by the limit were given back.
+	usage, _ := budgets.Usage(ctx, []budget.Key{{CampaignID: "small"}, {CampaignID: "large"}})
+	if usage["small"].LifetimeImpressions != 5 || usage["large"].LifetimeImpressions != 15 {
+		t.Errorf("Expected 5 and 15 impressions to be charged but got %+v", usage)
+	}
+	if usage["large"].LifetimeSpend != 15 {
+		t.Errorf("Expected 15 to be spent on large but got %v", usage["large"].LifetimeSpend)
+	}
+}
+
+func TestEvenPacing(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "even", Status: models.StatusActive, DailyImpressionBudget: 100, Pacing: models.PacingEven},
+		},
+		nil,
+	)
+
+	now := time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC)
+	svc := NewTargetingService(repo, WithClock(func() time.Time { return now }))
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	served := 0
+	for i := 0; i < 100; i++ {
+		campaigns, _ := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS"})
+		served += len(campaigns)
+	}
+	// A quarter of the day has passed, plus the pacing slack.
+	if served != 27 {
+		t.Errorf("Expected 27 impressions by 06:00 but got %d", served)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+
+	"targeting-engine/internal/creative"
+	"targeting-engine/internal/logging"
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+// WithCreativeStore sets where creative impressions and clicks are counted
+// for CTR rotation. The default is an in-memory store local to this
+// instance.
+func WithCreativeStore(store creative.Store) Option {
+	return func(s *TargetingService) {
+		s.creatives = store
+	}
+}
+
+// filterCreatives drops the campaigns that have creatives but none for the
+// request's size and language, or for the device's experiment arm.
+func filterCreatives(req models.DeliveryRequest, campaigns []*indexedCampaign) []*indexedCampaign {
+	if req.Size == "" && req.Language == "" && req.DeviceID == "" {
+		return campaigns
+	}
+
+	allowed := campaigns[:0:0]
+	for _, campaign := range campaigns {
+		if campaign.creatives == nil || len(matchingCreatives(req, campaign)) > 0 {
+			allowed = append(allowed, campaign)
+		}
+	}
+	return allowed
+}
+
+func matchingCreatives(req models.DeliveryRequest, campaign *indexedCampaign) []models.Creative {
+	arm := campaign.arm(req.DeviceID)
+	if req.Size == "" && req.Language == "" && (arm == nil || len(arm.Creatives) == 0) {
+		return campaign.creatives
+	}
+
+	var matched []models.Creative
+	for _, c := range campaign.creatives {
+		if c.Matches(req.Size, req.Language) && (arm == nil || arm.HasCreative(c.ID)) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// chooseCreative picks the creative a delivered campaign shows, by its
+// rotation, and counts the impression. It returns nil for campaigns without
+// creatives. If the store fails, CTR rotation falls back to what it has.
+func (s *TargetingService) chooseCreative(ctx context.Context, req models.DeliveryRequest, campaign *indexedCampaign) *models.Creative {
+	creatives := matchingCreatives(req, campaign)
+	if len(creatives) == 0 {
+		return nil
+	}
+
+	var stats map[string]creative.Stats
+	if campaign.CreativeRotation == models.RotationCTR && len(creatives) > 1 {
+		var err error
+		if stats, err = s.creatives.Stats(ctx, campaign.ID); err != nil {
+			logging.FromContext(ctx).Warn("Couldn't read creative stats", "campaign", campaign.ID, "error", err)
+		}
+	}
+	chosen := creative.Choose(campaign.CreativeRotation, creatives, stats, rand.Float64)
+
+	if err := s.creatives.AddImpression(ctx, campaign.ID, chosen.ID); err != nil {
+		logging.FromContext(ctx).Warn("Couldn't count creative impression", "campaign", campaign.ID, "creative", chosen.ID, "error", err)
+	}
+	return chosen
+}
+
+// setCreative makes ad show c instead of the campaign's own image and CTA.
+func setCreative(ad *models.CampaignResponse, c *models.Creative) {
+	ad.CreativeID = c.ID
+	ad.Type = c.Type
+	ad.Img = c.URL
+	ad.HTML = c.HTML
+	ad.Width = c.Width
+	ad.Height = c.Height
+	if c.CTA != "" {
+		ad.CTA = c.CTA
+	}
+}
+
+// RecordClick counts a click on a delivered creative, which CTR rotation
+// learns from. It fails with repository.ErrCreativeNotFound for creatives
+// of campaigns that aren't active.
+func (s *TargetingService) RecordClick(ctx context.Context, campaignID, creativeID string) error {
+	idx := s.index.Load()
+	if idx == nil {
+		return ErrNotReady
+	}
+	if !idx.hasCreative(campaignID, creativeID) {
+		return repository.ErrCreativeNotFound
+	}
+	return s.creatives.AddClick(ctx, campaignID, creativeID)
+}
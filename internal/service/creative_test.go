@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+func TestCreatives(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "music", Status: models.StatusActive, ImageURL: "https://music", CTA: "Listen"},
+			{ID: "plain", Status: models.StatusActive, ImageURL: "https://plain", CTA: "Open"},
+		},
+		nil,
+	)
+	for _, c := range []models.Creative{
+		{CampaignID: "music", ID: "banner", Type: models.CreativeImage, URL: "https://banner", Width: 320, Height: 50, Locale: "en"},
+		{CampaignID: "music", ID: "video", Type: models.CreativeVideo, URL: "https://video", Width: 300, Height: 250, CTA: "Ouvir", Locale: "pt"},
+	} {
+		if err := repo.SaveCreative(ctx, c); err != nil {
+			t.Fatalf("Failed to save creative %s: %v", c.ID, err)
+		}
+	}
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		size     string
+		language string
+		want     map[string]string
+	}{
+		{name: "Size", size: "300x250", want: map[string]string{"music": "video", "plain": ""}},
+		{name: "Language", language: "en-GB", want: map[string]string{"music": "banner", "plain": ""}},
+		{name: "No creative for the size", size: "728x90", want: map[string]string{"plain": ""}},
+		{name: "No creative for size and language", size: "300x250", language: "en", want: map[string]string{"plain": ""}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", Size: tc.size, Language: tc.language}
+			campaigns, err := svc.GetMatchingCampaigns(ctx, req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := make(map[string]string)
+			for _, c := range campaigns {
+				got[c.CID] = c.CreativeID
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Expected %v but got %+v", tc.want, campaigns)
+			}
+			for cid, crid := range tc.want {
+				if c, ok := got[cid]; !ok || c != crid {
+					t.Errorf("Expected campaign %s with creative %q but got %+v", cid, crid, campaigns)
+				}
+			}
+		})
+	}
+
+	campaigns, _ := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", Size: "300x250"})
+	if ad := campaigns[0]; ad.Img != "https://video" || ad.CTA != "Ouvir" || ad.Type != models.CreativeVideo || ad.Width != 300 {
+		t.Errorf("Expected the video creative's URL, CTA, type and size but got %+v", ad)
+	}
+
+	if err := svc.RecordClick(ctx, "music", "video"); err != nil {
+		t.Errorf("RecordClick failed: %v", err)
+	}
+	if err := svc.RecordClick(ctx, "plain", "video"); !errors.Is(err, repository.ErrCreativeNotFound) {
+		t.Errorf("Expected a click on another campaign's creative to fail but got %v", err)
+	}
+}
+
+func TestCTRRotation(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{{ID: "music", Status: models.StatusActive, CreativeRotation: models.RotationCTR}},
+		nil,
+	)
+	for _, id := range []string{"a", "b"} {
+		if err := repo.SaveCreative(ctx, models.Creative{CampaignID: "music", ID: id, Type: models.CreativeImage, URL: "https://" + id}); err != nil {
+			t.Fatalf("Failed to save creative %s: %v", id, err)
+		}
+	}
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	deliver := func() string {
+		campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS"})
+		if err != nil || len(campaigns) != 1 {
+			t.Fatalf("Expected one campaign but got %+v, %v", campaigns, err)
+		}
+		return campaigns[0].CreativeID
+	}
+
+	// Each creative is tried once, then b's click makes it the favourite.
+	if first, second := deliver(), deliver(); first != "a" || second != "b" {
+		t.Fatalf("Expected a and then b to be tried but got %s and %s", first, second)
+	}
+	if err := svc.RecordClick(ctx, "music", "b"); err != nil {
+		t.Fatalf("RecordClick failed: %v", err)
+	}
+	shown := map[string]int{}
+	for i := 0; i < 50; i++ {
+		shown[deliver()]++
+	}
+	if shown["b"] <= shown["a"] {
+		t.Errorf("Expected the clicked creative to be shown most but got %v", shown)
+	}
+}
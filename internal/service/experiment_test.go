@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"targeting-engine/internal/models"
+)
+
+func TestExperimentAssignment(t *testing.T) {
+	e := &models.Experiment{ID: "geo", Arms: []models.Arm{{ID: "control", Traffic: 50}, {ID: "variant", Traffic: 25}}}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		device := fmt.Sprintf("device-%d", i)
+		arm := e.Assign(device)
+		if again := e.Assign(device); arm != again {
+			t.Fatalf("Expected %s to stay in the same arm", device)
+		}
+		if arm == nil {
+			counts[""]++
+			continue
+		}
+		counts[arm.ID]++
+	}
+	for id, want := range map[string]int{"control": 5000, "variant": 2500, "": 2500} {
+		if got := counts[id]; got < want*9/10 || got > want*11/10 {
+			t.Errorf("Expected about %d devices in arm %q but got %d", want, id, got)
+		}
+	}
+
+	if e.Assign("") != nil {
+		t.Error("Expected requests without a device ID to be in no arm")
+	}
+}
+
+func TestExperimentDelivery(t *testing.T) {
+	ctx := context.Background()
+	experiment := &models.Experiment{ID: "geo", Arms: []models.Arm{
+		{ID: "control", Traffic: 50},
+		{ID: "canada", Traffic: 50, Creatives: []string{"maple"}, Rules: []models.TargetingRule{
+			{DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"CA"}},
+		}},
+	}}
+	repo := newMemoryRepository(t,
+		[]models.Campaign{{ID: "music", Status: models.StatusActive, Experiment: experiment}},
+		[]models.TargetingRule{{CampaignID: "music", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"US"}}},
+	)
+	for _, id := range []string{"eagle", "maple"} {
+		if err := repo.SaveCreative(ctx, models.Creative{CampaignID: "music", ID: id, Type: models.CreativeImage, URL: "https://" + id}); err != nil {
+			t.Fatalf("Failed to save creative %s: %v", id, err)
+		}
+	}
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// Find a device in each arm.
+	devices := make(map[string]string)
+	for i := 0; len(devices) < 2; i++ {
+		device := fmt.Sprintf("device-%d", i)
+		devices[experiment.Assign(device).ID] = device
+	}
+
+	deliver := func(device, country string) []models.CampaignResponse {
+		campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", OS: "iOS", Country: country, DeviceID: device})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return campaigns
+	}
+
+	if got := deliver(devices["control"], "CA"); len(got) != 0 {
+		t.Errorf("Expected the control arm to keep the campaign's rules but got %+v", got)
+	}
+	if got := deliver(devices["canada"], "US"); len(got) != 0 {
+		t.Errorf("Expected the canada arm's rule to replace the campaign's but got %+v", got)
+	}
+	got := deliver(devices["canada"], "CA")
+	if len(got) != 1 || got[0].Experiment != "geo" || got[0].Arm != "canada" || got[0].CreativeID != "maple" {
+		t.Errorf("Expected the canada arm, tagged and limited to its creative, but got %+v", got)
+	}
+	got = deliver("", "US")
+	if len(got) != 1 || got[0].Experiment != "" || got[0].Arm != "" {
+		t.Errorf("Expected requests without a device ID to get the untagged campaign but got %+v", got)
+	}
+}
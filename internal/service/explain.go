@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"targeting-engine/internal/budget"
+	"targeting-engine/internal/models"
+)
+
+// Explain checks every campaign, active or not, against req as
+// GetMatchingCampaigns would and tells why each would or wouldn't be
+// delivered. Nothing is recorded or charged. Campaigns are sorted by ID.
+func (s *TargetingService) Explain(ctx context.Context, req models.DeliveryRequest) (*models.Explanation, error) {
+	ctx, span := tracer.Start(ctx, "TargetingService.Explain")
+	defer span.End()
+
+	if req.App == "" || req.OS == "" || req.Country == "" {
+		recordSpan(span, ErrInvalidRequest)
+		return nil, ErrInvalidRequest
+	}
+
+	// The index is swapped while mu is held, so it was built from data.
+	s.mu.Lock()
+	idx, data := s.index.Load(), s.data
+	s.mu.Unlock()
+	if idx == nil {
+		recordSpan(span, ErrNotReady)
+		return nil, ErrNotReady
+	}
+
+	rules := make(map[string]map[models.DimensionType]models.TargetingRule)
+	for _, rule := range data.rules {
+		if rules[rule.CampaignID] == nil {
+			rules[rule.CampaignID] = make(map[models.DimensionType]models.TargetingRule)
+		}
+		rules[rule.CampaignID][rule.DimensionType] = rule
+	}
+	expressions := make(map[string]models.Expression)
+	for _, expr := range data.expressions {
+		expressions[expr.CampaignID] = expr.Expression
+	}
+	creatives := make(map[string][]models.Creative)
+	for _, c := range data.creatives {
+		creatives[c.CampaignID] = append(creatives[c.CampaignID], c)
+	}
+
+	campaigns := append([]models.Campaign{}, data.campaigns...)
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].ID < campaigns[j].ID })
+
+	now := s.now()
+	explanation := &models.Explanation{Request: req, Campaigns: make([]models.CampaignExplanation, 0, len(campaigns))}
+	for _, campaign := range campaigns {
+		compiled, ok := idx.campaign(campaign.ID)
+		var loadErr error
+		if !ok {
+			compiled, loadErr = compileCampaign(campaign, creatives[campaign.ID])
+		}
+		var expr *models.Expression
+		if e, ok := expressions[campaign.ID]; ok {
+			expr = &e
+		}
+		explanation.Campaigns = append(explanation.Campaigns, s.explainCampaign(ctx, req, compiled, loadErr, rules[campaign.ID], expr, now))
+	}
+	return explanation, nil
+}
+
+// compileCampaign compiles a campaign missing from the index, because it
+// isn't active or because buildIndex couldn't compile it, the way
+// buildIndex would have. On error the campaign has no location or
+// schedule.
+func compileCampaign(campaign models.Campaign, creatives []models.Creative) (*indexedCampaign, error) {
+	compiled := &indexedCampaign{Campaign: campaign, creatives: append([]models.Creative{}, creatives...)}
+	sort.Slice(compiled.creatives, func(i, j int) bool { return compiled.creatives[i].ID < compiled.creatives[j].ID })
+
+	location, err := campaign.Location()
+	if err != nil {
+		return compiled, err
+	}
+	sched, err := compileSchedule(campaign, location)
+	if err != nil {
+		return compiled, err
+	}
+	compiled.location, compiled.schedule = location, sched
+	return compiled, nil
+}
+
+// explainCampaign runs every check of one campaign. loadErr is why the
+// campaign couldn't be compiled, if it couldn't.
+func (s *TargetingService) explainCampaign(ctx context.Context, req models.DeliveryRequest, campaign *indexedCampaign, loadErr error,
+	rules map[models.DimensionType]models.TargetingRule, expr *models.Expression, now time.Time) models.CampaignExplanation {
+	e := models.CampaignExplanation{CID: campaign.ID, Status: campaign.Status}
+	add := func(check models.Check) {
+		e.Checks = append(e.Checks, check)
+	}
+
+	add(models.Check{Check: models.CheckStatus, Pass: campaign.Status == models.StatusActive, Reason: "campaign is " + string(campaign.Status)})
+
+	if loadErr != nil {
+		add(models.Check{Check: models.CheckLoad, Reason: "invalid time zone or schedule: " + loadErr.Error()})
+	} else {
+		check := models.Check{Check: models.CheckSchedule, Pass: true, Reason: "runs now"}
+		if reason := campaign.schedule.inactiveReason(now); reason != "" {
+			check.Pass, check.Reason = false, reason
+		}
+		add(check)
+	}
+
+	// An experiment arm's rules replace the campaign's for their dimensions.
+	arm := campaign.arm(req.DeviceID)
+	if arm != nil {
+		e.Experiment, e.Arm = campaign.Experiment.ID, arm.ID
+		effective := make(map[models.DimensionType]models.TargetingRule, len(rules)+len(arm.Rules))
+		for dimension, rule := range rules {
+			effective[dimension] = rule
+		}
+		for _, rule := range arm.Rules {
+			effective[rule.DimensionType] = rule
+		}
+		rules = effective
+	}
+	for _, dimension := range models.Dimensions {
+		if rule, ok := rules[dimension]; ok {
+			add(explainRule(req, rule))
+		}
+	}
+
+	if expr != nil {
+		check := models.Check{Check: models.CheckExpression}
+		compiled, err := compileExpression(*expr)
+		switch {
+		case err != nil:
+			check.Reason = "invalid expression: " + err.Error()
+		case compiled.eval(req):
+			check.Pass, check.Reason = true, "expression holds"
+		default:
+			check.Reason = "expression doesn't hold"
+		}
+		add(check)
+	}
+
+	if len(campaign.creatives) > 0 {
+		matched := matchingCreatives(req, campaign)
+		add(models.Check{Check: models.CheckCreatives, Pass: len(matched) > 0,
+			Reason: fmt.Sprintf("%d of %d creatives fit the request", len(matched), len(campaign.creatives))})
+	}
+
+	if req.DeviceID != "" && len(campaign.FrequencyCaps) > 0 {
+		add(s.explainFrequencyCaps(ctx, req.DeviceID, campaign, now))
+	}
+	if campaign.HasBudget() && loadErr == nil {
+		add(s.explainBudget(ctx, campaign, now))
+	}
+
+	if a := s.auction.Load(); a != nil {
+		floor := a.Floor(req.App, req.Country)
+		check := models.Check{Check: models.CheckAuction, Pass: campaign.BidCPM >= floor}
+		if check.Pass {
+			check.Reason = fmt.Sprintf("bid %v is at or above the floor of %v", campaign.BidCPM, floor)
+		} else {
+			check.Reason = fmt.Sprintf("bid %v is below the floor of %v", campaign.BidCPM, floor)
+		}
+		add(check)
+	}
+
+	e.Eligible = true
+	for _, check := range e.Checks {
+		if !check.Pass {
+			e.Eligible, e.Reason = false, check.Reason
+			break
+		}
+	}
+	return e
+}
+
+// explainRule checks a targeting rule against the request's value for its
+// dimension.
+func explainRule(req models.DeliveryRequest, rule models.TargetingRule) models.Check {
+	value := requestValue(req, rule.DimensionType)
+	check := models.Check{
+		Check:        models.CheckRule,
+		Dimension:    rule.DimensionType,
+		RuleType:     rule.RuleType,
+		Values:       rule.Values,
+		RequestValue: &value,
+	}
+	if !rule.DimensionType.IsVersion() {
+		check.MatchType = rule.MatchType.OrDefault()
+	}
+
+	matcher, err := compileMatcher(rule.DimensionType, rule.MatchType, rule.Values)
+	if err != nil {
+		check.Reason = fmt.Sprintf("invalid %s rule: %v", rule.DimensionType, err)
+		return check
+	}
+	matched := matcher.matches(value)
+	check.Pass = matched == (rule.RuleType == models.Include)
+
+	dimension := strings.ToLower(string(rule.DimensionType))
+	switch {
+	case value == "":
+		check.Reason = "request has no " + dimension
+	case rule.RuleType == models.Include && matched:
+		check.Reason = fmt.Sprintf("%s %q is included", dimension, value)
+	case rule.RuleType == models.Include:
+		check.Reason = fmt.Sprintf("%s %q is not included", dimension, value)
+	case matched:
+		check.Reason = fmt.Sprintf("%s %q is excluded", dimension, value)
+	default:
+		check.Reason = fmt.Sprintf("%s %q is not excluded", dimension, value)
+	}
+	return check
+}
+
+func (s *TargetingService) explainFrequencyCaps(ctx context.Context, deviceID string, campaign *indexedCampaign, now time.Time) models.Check {
+	var longest time.Duration
+	for _, fc := range campaign.FrequencyCaps {
+		if d := fc.Period.Duration(); d > longest {
+			longest = d
+		}
+	}
+	exposures, err := s.frequency.Exposures(ctx, deviceID, []string{campaign.ID}, now.Add(-longest))
+	switch {
+	case err != nil:
+		// Delivery serves uncapped when the store fails.
+		return models.Check{Check: models.CheckFrequencyCap, Pass: true, Reason: "couldn't read exposures, served uncapped: " + err.Error()}
+	case withinCaps(campaign.FrequencyCaps, exposures[campaign.ID], now):
+		return models.Check{Check: models.CheckFrequencyCap, Pass: true, Reason: "device is within the frequency caps"}
+	default:
+		return models.Check{Check: models.CheckFrequencyCap, Reason: "device has reached a frequency cap"}
+	}
+}
+
+func (s *TargetingService) explainBudget(ctx context.Context, campaign *indexedCampaign, now time.Time) models.Check {
+	key := budget.Key{CampaignID: campaign.ID, Day: budget.Day(now, campaign.location)}
+	usage, err := s.budget.Usage(ctx, []budget.Key{key})
+	if err != nil {
+		// Delivery skips budgeted campaigns when the store fails.
+		return models.Check{Check: models.CheckBudget, Reason: "couldn't read budget usage: " + err.Error()}
+	}
+
+	c, used := &campaign.Campaign, usage[campaign.ID]
+	switch {
+	case budget.Eligible(c, used, now, campaign.location):
+		return models.Check{Check: models.CheckBudget, Pass: true, Reason: "budget left"}
+	case budget.Exhausted(c, used):
+		return models.Check{Check: models.CheckBudget, Reason: "lifetime budget is used up"}
+	case (c.DailyImpressionBudget > 0 && used.DailyImpressions >= c.DailyImpressionBudget) ||
+		(c.DailySpendBudget > 0 && used.DailySpend >= c.DailySpendBudget):
+		return models.Check{Check: models.CheckBudget, Reason: "daily budget is used up"}
+	default:
+		return models.Check{Check: models.CheckBudget, Reason: "held back by even pacing"}
+	}
+}
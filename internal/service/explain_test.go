@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	end := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "music", Status: models.StatusActive},
+			{ID: "news", Status: models.StatusActive},
+			{ID: "paused", Status: models.StatusInactive},
+			{ID: "over", Status: models.StatusActive, EndAt: &end},
+		},
+		[]models.TargetingRule{
+			{CampaignID: "music", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"US", "CA"}},
+			{CampaignID: "music", DimensionType: models.DimensionOS, RuleType: models.Exclude, Values: []string{"web"}},
+			{CampaignID: "news", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"UK"}},
+			{CampaignID: "news", DimensionType: models.DimensionOSVersion, RuleType: models.Include, Values: []string{">=12"}},
+		},
+	)
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	svc := NewTargetingService(repo, WithClock(func() time.Time { return now }))
+
+	req := models.DeliveryRequest{App: "a", OS: "ios", Country: "US"}
+	if _, err := svc.Explain(ctx, req); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Expected ErrNotReady before the first refresh but got %v", err)
+	}
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if _, err := svc.Explain(ctx, models.DeliveryRequest{App: "a", OS: "ios"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest without a country but got %v", err)
+	}
+
+	explanation, err := svc.Explain(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reasons := make(map[string]string)
+	var ids []string
+	for _, c := range explanation.Campaigns {
+		ids = append(ids, c.CID)
+		reasons[c.CID] = c.Reason
+		if c.Eligible != (c.Reason == "") {
+			t.Errorf("Expected %s to have a reason only when it isn't eligible but got %+v", c.CID, c)
+		}
+	}
+	if want := []string{"music", "news", "over", "paused"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected campaigns %v but got %v", want, ids)
+	}
+	expected := map[string]string{
+		"music":  "",
+		"news":   `country "US" is not included`,
+		"over":   "ended at 2024-06-01T00:00:00Z",
+		"paused": "campaign is INACTIVE",
+	}
+	for id, want := range expected {
+		if got := reasons[id]; got != want {
+			t.Errorf("Expected %s's reason to be %q but got %q", id, want, got)
+		}
+	}
+
+	// Explain agrees with delivery.
+	delivered, err := svc.GetMatchingCampaigns(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(delivered) != 1 || delivered[0].CID != "music" {
+		t.Errorf("Expected only music to be delivered but got %+v", delivered)
+	}
+
+	news := explanation.Campaigns[1]
+	if len(news.Checks) != 4 {
+		t.Fatalf("Expected status, schedule and two rule checks but got %+v", news.Checks)
+	}
+	version := news.Checks[3]
+	if version.Dimension != models.DimensionOSVersion || version.Pass || version.RequestValue == nil || *version.RequestValue != "" {
+		t.Errorf("Expected the os_version rule to fail on an empty request value but got %+v", version)
+	}
+	if version.Reason != "request has no os_version" {
+		t.Errorf("Expected the os_version rule's reason to name the missing value but got %q", version.Reason)
+	}
+	if version.MatchType != "" {
+		t.Errorf("Expected version rules to have no match type but got %q", version.MatchType)
+	}
+}
+
+func TestExplainExperiment(t *testing.T) {
+	ctx := context.Background()
+	experiment := &models.Experiment{ID: "geo", Arms: []models.Arm{{ID: "canada", Traffic: 100, Rules: []models.TargetingRule{
+		{DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"CA"}},
+	}}}}
+	repo := newMemoryRepository(t,
+		[]models.Campaign{{ID: "music", Status: models.StatusActive, Experiment: experiment}},
+		[]models.TargetingRule{{CampaignID: "music", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"US"}}},
+	)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	explanation, err := svc.Explain(ctx, models.DeliveryRequest{App: "a", OS: "ios", Country: "CA", DeviceID: "device"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	music := explanation.Campaigns[0]
+	if !music.Eligible || music.Experiment != "geo" || music.Arm != "canada" {
+		t.Errorf("Expected the arm's rule to replace the campaign's but got %+v", music)
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/version"
+)
+
+type exprKind int
+
+const (
+	exprAnd exprKind = iota
+	exprOr
+	exprNot
+	exprPredicate
+)
+
+// compiledExpr is an expression ready to be evaluated against requests, with
+// each predicate's values turned into a matcher.
+type compiledExpr struct {
+	kind      exprKind
+	children  []*compiledExpr
+	dimension models.DimensionType
+	matcher   valueMatcher
+}
+
+// valueMatcher decides whether a request value satisfies a predicate.
+type valueMatcher interface {
+	matches(value string) bool
+}
+
+// valueSet matches values case-insensitively against a fixed list.
+type valueSet map[string]bool
+
+func (s valueSet) matches(value string) bool {
+	return s[strings.ToLower(value)]
+}
+
+// prefixes and suffixes match values that start or end with one of the
+// lowercased patterns.
+type prefixes []string
+
+func (p prefixes) matches(value string) bool {
+	value = strings.ToLower(value)
+	for _, prefix := range p {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type suffixes []string
+
+func (s suffixes) matches(value string) bool {
+	value = strings.ToLower(value)
+	for _, suffix := range s {
+		if strings.HasSuffix(value, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns matches values against one regular expression built from every
+// glob or regex value.
+type patterns struct {
+	re *regexp.Regexp
+}
+
+func (p patterns) matches(value string) bool {
+	return p.re.MatchString(value)
+}
+
+// globToRegex translates a glob where * matches any run of characters and ?
+// a single one into an anchored regular expression.
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// versionRanges matches versions that satisfy any of the constraints. Values
+// that are missing or don't parse as versions never match.
+type versionRanges []*version.Constraint
+
+func (r versionRanges) matches(value string) bool {
+	v, err := version.Parse(value)
+	if err != nil {
+		return false
+	}
+	for _, constraint := range r {
+		if constraint.Check(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileExpression(expr models.Expression) (*compiledExpr, error) {
+	switch {
+	case expr.And != nil:
+		children, err := compileAll(expr.And)
+		return &compiledExpr{kind: exprAnd, children: children}, err
+	case expr.Or != nil:
+		children, err := compileAll(expr.Or)
+		return &compiledExpr{kind: exprOr, children: children}, err
+	case expr.Not != nil:
+		child, err := compileExpression(*expr.Not)
+		return &compiledExpr{kind: exprNot, children: []*compiledExpr{child}}, err
+	}
+
+	matcher, err := compileMatcher(expr.Dimension, expr.Match, expr.Values)
+	if err != nil {
+		return nil, err
+	}
+	return &compiledExpr{kind: exprPredicate, dimension: expr.Dimension, matcher: matcher}, nil
+}
+
+func compileAll(exprs []models.Expression) ([]*compiledExpr, error) {
+	compiled := make([]*compiledExpr, 0, len(exprs))
+	for _, expr := range exprs {
+		c, err := compileExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compileMatcher(dimension models.DimensionType, match models.MatchType, values []string) (valueMatcher, error) {
+	if dimension.IsVersion() {
+		ranges := make(versionRanges, 0, len(values))
+		for _, value := range values {
+			constraint, err := version.ParseConstraint(value)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, constraint)
+		}
+		return ranges, nil
+	}
+
+	switch match.OrDefault() {
+	case models.MatchExact:
+		set := make(valueSet, len(values))
+		for _, value := range normalizedValues(values) {
+			set[value] = true
+		}
+		return set, nil
+	case models.MatchPrefix:
+		return prefixes(normalizedValues(values)), nil
+	case models.MatchSuffix:
+		return suffixes(normalizedValues(values)), nil
+	case models.MatchGlob, models.MatchRegex:
+		alternatives := make([]string, 0, len(values))
+		for _, value := range values {
+			if match == models.MatchGlob {
+				value = globToRegex(value)
+			}
+			if _, err := regexp.Compile(value); err != nil {
+				return nil, err
+			}
+			alternatives = append(alternatives, "(?:"+value+")")
+		}
+		re, err := regexp.Compile("(?i)" + strings.Join(alternatives, "|"))
+		if err != nil {
+			return nil, err
+		}
+		return patterns{re: re}, nil
+	default:
+		return nil, fmt.Errorf("unknown match type %s", match)
+	}
+}
+
+func (e *compiledExpr) eval(req models.DeliveryRequest) bool {
+	switch e.kind {
+	case exprAnd:
+		for _, child := range e.children {
+			if !child.eval(req) {
+				return false
+			}
+		}
+		return true
+	case exprOr:
+		for _, child := range e.children {
+			if child.eval(req) {
+				return true
+			}
+		}
+		return false
+	case exprNot:
+		return !e.children[0].eval(req)
+	default:
+		return e.matcher.matches(requestValue(req, e.dimension))
+	}
+}
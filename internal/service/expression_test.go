@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"targeting-engine/internal/models"
+)
+
+func TestExpressionTargeting(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	var expr models.Expression
+	err := json.Unmarshal([]byte(`{"or": [
+		{"and": [{"dimension": "COUNTRY", "values": ["US", "CA"]}, {"dimension": "OS", "values": ["iOS"]}]},
+		{"dimension": "APP", "values": ["x", "y"]}
+	]}`), &expr)
+	if err != nil {
+		t.Fatalf("Failed to decode expression: %v", err)
+	}
+	if err := expr.Validate(); err != nil {
+		t.Fatalf("Expected a valid expression but got %v", err)
+	}
+
+	repo.SaveCampaign(ctx, models.Campaign{ID: "expr", Status: models.StatusActive})
+	repo.SaveTargetingExpression(ctx, models.TargetingExpression{CampaignID: "expr", Expression: expr})
+	// Flat rules still apply on top of the expression.
+	repo.SaveTargetingRule(ctx, models.TargetingRule{CampaignID: "expr", DimensionType: models.DimensionApp, RuleType: models.Exclude, Values: []string{"y"}})
+
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request models.DeliveryRequest
+		matches bool
+	}{
+		{name: "US on iOS", request: models.DeliveryRequest{App: "z", Country: "us", OS: "iOS"}, matches: true},
+		{name: "US on Android", request: models.DeliveryRequest{App: "z", Country: "US", OS: "Android"}, matches: false},
+		{name: "App x anywhere", request: models.DeliveryRequest{App: "x", Country: "DE", OS: "Android"}, matches: true},
+		{name: "App y is excluded by the flat rule", request: models.DeliveryRequest{App: "y", Country: "DE", OS: "Android"}, matches: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			campaigns, err := svc.GetMatchingCampaigns(ctx, tc.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			found := false
+			for _, c := range campaigns {
+				found = found || c.CID == "expr"
+			}
+			if found != tc.matches {
+				t.Errorf("Expected match %v but got campaigns %v", tc.matches, campaigns)
+			}
+		})
+	}
+}
+
+func TestExpressionValidation(t *testing.T) {
+	invalid := []string{
+		`{}`,
+		`{"and": []}`,
+		`{"and": [{"dimension": "OS", "values": ["iOS"]}], "dimension": "APP", "values": ["x"]}`,
+		`{"dimension": "DEVICE", "values": ["x"]}`,
+		`{"not": {"dimension": "OS", "values": []}}`,
+	}
+	for _, raw := range invalid {
+		var expr models.Expression
+		if err := json.Unmarshal([]byte(raw), &expr); err != nil {
+			t.Fatalf("Failed to decode %s: %v", raw, err)
+		}
+		if err := expr.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestVersionTargeting(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "android10", Status: models.StatusActive},
+			{ID: "app42", Status: models.StatusActive},
+		},
+		[]models.TargetingRule{
+			{CampaignID: "android10", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"Android"}},
+			{CampaignID: "android10", DimensionType: models.DimensionOSVersion, RuleType: models.Include, Values: []string{">=10"}},
+			{CampaignID: "app42", DimensionType: models.DimensionAppVersion, RuleType: models.Include, Values: []string{"4.2.0 - 5.x"}},
+			{CampaignID: "app42", DimensionType: models.DimensionAppVersion, RuleType: models.Include, Values: []string{"4.2.0 - 5.x", "7.*"}},
+		},
+	)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		request     models.DeliveryRequest
+		expectedIDs []string
+	}{
+		{name: "Android 9", request: models.DeliveryRequest{App: "a", Country: "US", OS: "Android", OSVersion: "9"}},
+		{name: "Android 12 on app 5.1", request: models.DeliveryRequest{App: "a", Country: "US", OS: "Android", OSVersion: "12.1", AppVersion: "5.1"}, expectedIDs: []string{"android10", "app42"}},
+		{name: "No versions sent", request: models.DeliveryRequest{App: "a", Country: "US", OS: "Android"}},
+		{name: "App 7.3 on iOS", request: models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", OSVersion: "17", AppVersion: "7.3"}, expectedIDs: []string{"app42"}},
+		{name: "App 6.0", request: models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", AppVersion: "6.0"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			campaigns, err := svc.GetMatchingCampaigns(ctx, tc.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(campaigns) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %v but got %v", tc.expectedIDs, campaigns)
+			}
+			for i, id := range tc.expectedIDs {
+				if campaigns[i].CID != id {
+					t.Errorf("Expected campaign ID %s at %d but got %s", id, i, campaigns[i].CID)
+				}
+			}
+		})
+	}
+
+	invalid := models.TargetingRule{CampaignID: "app42", DimensionType: models.DimensionAppVersion, RuleType: models.Include, Values: []string{">=four"}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected an invalid version range to be rejected")
+	}
+}
+
+func TestMatchTypes(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "no-gametion", Status: models.StatusActive},
+			{ID: "games", Status: models.StatusActive},
+			{ID: "regex", Status: models.StatusActive},
+			{ID: "lite", Status: models.StatusActive},
+		},
+		[]models.TargetingRule{
+			{CampaignID: "no-gametion", DimensionType: models.DimensionApp, RuleType: models.Exclude, MatchType: models.MatchPrefix, Values: []string{"com.gametion."}},
+			{CampaignID: "games", DimensionType: models.DimensionApp, RuleType: models.Include, MatchType: models.MatchGlob, Values: []string{"com.*.game?"}},
+			{CampaignID: "regex", DimensionType: models.DimensionApp, RuleType: models.Include, MatchType: models.MatchRegex, Values: []string{`^com\.(king|supercell)\.`}},
+			{CampaignID: "lite", DimensionType: models.DimensionApp, RuleType: models.Include, MatchType: models.MatchSuffix, Values: []string{".lite"}},
+		},
+	)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	tests := []struct {
+		app         string
+		expectedIDs []string
+	}{
+		{app: "com.gametion.ludokinggame", expectedIDs: []string{}},
+		{app: "com.Gametion.Other", expectedIDs: []string{}},
+		{app: "com.rovio.games", expectedIDs: []string{"games", "no-gametion"}},
+		{app: "com.King.candycrush", expectedIDs: []string{"no-gametion", "regex"}},
+		{app: "com.facebook.lite", expectedIDs: []string{"lite", "no-gametion"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.app, func(t *testing.T) {
+			campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: tc.app, Country: "US", OS: "Android"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(campaigns) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %v but got %v", tc.expectedIDs, campaigns)
+			}
+			for i, id := range tc.expectedIDs {
+				if campaigns[i].CID != id {
+					t.Errorf("Expected campaign ID %s at %d but got %s", id, i, campaigns[i].CID)
+				}
+			}
+		})
+	}
+
+	invalid := []models.TargetingRule{
+		{CampaignID: "regex", DimensionType: models.DimensionApp, RuleType: models.Include, MatchType: models.MatchRegex, Values: []string{"com.(king"}},
+		{CampaignID: "regex", DimensionType: models.DimensionApp, RuleType: models.Include, MatchType: "FUZZY", Values: []string{"com"}},
+		{CampaignID: "regex", DimensionType: models.DimensionOSVersion, RuleType: models.Include, MatchType: models.MatchPrefix, Values: []string{"10"}},
+	}
+	for _, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", rule)
+		}
+	}
+}
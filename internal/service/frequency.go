@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"targeting-engine/internal/frequency"
+	"targeting-engine/internal/logging"
+	"targeting-engine/internal/models"
+)
+
+// defaultFrequencyDevices is how many devices the default in-memory
+// frequency store remembers.
+const defaultFrequencyDevices = 1_000_000
+
+// WithFrequencyStore sets where frequency cap exposures are kept. The default
+// is an in-memory store local to this instance.
+func WithFrequencyStore(store frequency.Store) Option {
+	return func(s *TargetingService) {
+		s.frequency = store
+	}
+}
+
+// filterFrequencyCaps drops the campaigns the device has already been shown
+// as often as one of their caps allows. Requests without a device ID can't be
+// capped. If the store fails, campaigns are served rather than dropped.
+func (s *TargetingService) filterFrequencyCaps(ctx context.Context, deviceID string, campaigns []*indexedCampaign, now time.Time) []*indexedCampaign {
+	if deviceID == "" {
+		return campaigns
+	}
+
+	var capped []string
+	var longest time.Duration
+	for _, campaign := range campaigns {
+		for _, fc := range campaign.FrequencyCaps {
+			if len(capped) == 0 || capped[len(capped)-1] != campaign.ID {
+				capped = append(capped, campaign.ID)
+			}
+			if d := fc.Period.Duration(); d > longest {
+				longest = d
+			}
+		}
+	}
+	if len(capped) == 0 {
+		return campaigns
+	}
+
+	exposures, err := s.frequency.Exposures(ctx, deviceID, capped, now.Add(-longest))
+	if err != nil {
+		logging.FromContext(ctx).Warn("Couldn't read frequency caps, serving uncapped", "error", err)
+		return campaigns
+	}
+
+	allowed := campaigns[:0:0]
+	for _, campaign := range campaigns {
+		if withinCaps(campaign.FrequencyCaps, exposures[campaign.ID], now) {
+			allowed = append(allowed, campaign)
+		}
+	}
+	return allowed
+}
+
+func withinCaps(caps models.FrequencyCaps, shown []time.Time, now time.Time) bool {
+	return frequency.Allows(storeCaps(caps), shown, now)
+}
+
+func storeCaps(caps models.FrequencyCaps) []frequency.Cap {
+	result := make([]frequency.Cap, len(caps))
+	for i, fc := range caps {
+		result[i] = frequency.Cap{Max: fc.Max, Period: fc.Period.Duration()}
+	}
+	return result
+}
+
+// recordExposures notes that the device was shown the capped campaigns and
+// drops those whose caps were reached by a concurrent request since
+// filterFrequencyCaps read them.
+func (s *TargetingService) recordExposures(ctx context.Context, deviceID string, campaigns []*indexedCampaign, now time.Time) []*indexedCampaign {
+	if deviceID == "" {
+		return campaigns
+	}
+
+	caps := make(map[string][]frequency.Cap)
+	for _, campaign := range campaigns {
+		if len(campaign.FrequencyCaps) > 0 {
+			caps[campaign.ID] = storeCaps(campaign.FrequencyCaps)
+		}
+	}
+	if len(caps) == 0 {
+		return campaigns
+	}
+
+	recorded, err := s.frequency.Record(ctx, deviceID, caps, now)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Couldn't record frequency cap exposures", "error", err)
+		return campaigns
+	}
+
+	delivered := campaigns[:0:0]
+	for _, campaign := range campaigns {
+		if _, capped := caps[campaign.ID]; !capped || recorded[campaign.ID] {
+			delivered = append(delivered, campaign)
+		}
+	}
+	return delivered
+}
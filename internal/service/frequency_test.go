@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+func TestFrequencyCaps(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "capped", Status: models.StatusActive, FrequencyCaps: models.FrequencyCaps{
+				{Max: 2, Period: models.CapPerHour},
+				{Max: 3, Period: models.CapPerDay},
+			}},
+			{ID: "uncapped", Status: models.StatusActive},
+		},
+		nil,
+	)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewTargetingService(repo, WithClock(func() time.Time { return now }))
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	deliver := func(deviceID string) []string {
+		t.Helper()
+		campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", DeviceID: deviceID})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var ids []string
+		for _, c := range campaigns {
+			ids = append(ids, c.CID)
+		}
+		return ids
+	}
+
+	for i := 0; i < 2; i++ {
+		if ids := deliver("device"); len(ids) != 2 {
+			t.Fatalf("Expected both campaigns on delivery %d but got %v", i+1, ids)
+		}
+	}
+	if ids := deliver("device"); len(ids) != 1 || ids[0] != "uncapped" {
+		t.Fatalf("Expected the hourly cap to hold but got %v", ids)
+	}
+	if ids := deliver("another-device"); len(ids) != 2 {
+		t.Fatalf("Expected caps to be per device but got %v", ids)
+	}
+	if ids := deliver(""); len(ids) != 2 {
+		t.Fatalf("Expected requests without a device ID to be uncapped but got %v", ids)
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if ids := deliver("device"); len(ids) != 2 {
+		t.Fatalf("Expected the hourly cap to reset but got %v", ids)
+	}
+	if ids := deliver("device"); len(ids) != 1 {
+		t.Fatalf("Expected the daily cap to hold but got %v", ids)
+	}
+}
+
+func TestFrequencyCapsConcurrentDeliveries(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{{ID: "capped", Status: models.StatusActive, FrequencyCaps: models.FrequencyCaps{{Max: 2, Period: models.CapPerHour}}}},
+		nil,
+	)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	var delivered atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", DeviceID: "device"})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			delivered.Add(int32(len(campaigns)))
+		}()
+	}
+	wg.Wait()
+	if n := delivered.Load(); n != 2 {
+		t.Errorf("Expected concurrent deliveries to respect the cap of 2 but got %d", n)
+	}
+}
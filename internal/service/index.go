@@ -0,0 +1,368 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+// indexedDimensions lists the request dimensions the index knows how to look up.
+var indexedDimensions = []models.DimensionType{
+	models.DimensionApp,
+	models.DimensionCountry,
+	models.DimensionOS,
+}
+
+var indexedDimensionSet = func() map[models.DimensionType]bool {
+	set := make(map[models.DimensionType]bool, len(indexedDimensions))
+	for _, dim := range indexedDimensions {
+		set[dim] = true
+	}
+	return set
+}()
+
+// campaignIndex is an immutable, compiled view of the active campaigns and their
+// targeting rules. It is built once per refresh and swapped atomically, so
+// requests read it without locking and without touching the database.
+type campaignIndex struct {
+	// campaigns holds the active campaigns in repository order.
+	campaigns []*indexedCampaign
+	// byID maps campaign IDs to their position in campaigns.
+	byID map[string]int
+
+	// required is the number of INCLUDE rules each campaign must satisfy.
+	required []int
+
+	// unconstrained lists campaigns without any INCLUDE rule. They are
+	// candidates for every request unless an EXCLUDE rule removes them.
+	unconstrained []int
+
+	// evaluated holds the campaigns with a targeting expression. They can't
+	// be looked up by value and are evaluated against every request.
+	evaluated []evaluatedCampaign
+
+	// include and exclude map a dimension and a lowercased value to the
+	// campaigns whose INCLUDE or EXCLUDE rule lists that value.
+	include map[models.DimensionType]map[string][]int
+	exclude map[models.DimensionType]map[string][]int
+
+	loadedAt time.Time
+}
+
+// indexedCampaign is a campaign together with the settings compiled for it.
+type indexedCampaign struct {
+	models.Campaign
+	location *time.Location
+	schedule *schedule
+	// creatives are the campaign's creatives ordered by ID, if it has any.
+	creatives []models.Creative
+}
+
+type evaluatedCampaign struct {
+	pos  int
+	expr *compiledExpr
+	// arms holds the targeting of the experiment arms that replace some of
+	// the campaign's rules, by arm ID.
+	arms map[string]*compiledExpr
+}
+
+// targetingData is everything an index is built from.
+type targetingData struct {
+	campaigns   []models.Campaign
+	rules       []models.TargetingRule
+	expressions []models.TargetingExpression
+	creatives   []models.Creative
+}
+
+func buildIndex(data targetingData, loadedAt time.Time) *campaignIndex {
+	idx := &campaignIndex{
+		byID:     make(map[string]int),
+		include:  make(map[models.DimensionType]map[string][]int),
+		exclude:  make(map[models.DimensionType]map[string][]int),
+		loadedAt: loadedAt,
+	}
+	for _, dim := range indexedDimensions {
+		idx.include[dim] = make(map[string][]int)
+		idx.exclude[dim] = make(map[string][]int)
+	}
+
+	// A campaign has at most one rule per dimension; the last one read wins.
+	rulesByCampaign := make(map[string]map[models.DimensionType]models.TargetingRule)
+	for _, rule := range data.rules {
+		if !rule.DimensionType.Valid() {
+			continue
+		}
+		if _, ok := rulesByCampaign[rule.CampaignID]; !ok {
+			rulesByCampaign[rule.CampaignID] = make(map[models.DimensionType]models.TargetingRule)
+		}
+		rulesByCampaign[rule.CampaignID][rule.DimensionType] = rule
+	}
+
+	expressionByCampaign := make(map[string]models.Expression)
+	for _, expr := range data.expressions {
+		expressionByCampaign[expr.CampaignID] = expr.Expression
+	}
+
+	creativesByCampaign := make(map[string][]models.Creative)
+	for _, creative := range data.creatives {
+		creativesByCampaign[creative.CampaignID] = append(creativesByCampaign[creative.CampaignID], creative)
+	}
+	for _, creatives := range creativesByCampaign {
+		sort.Slice(creatives, func(i, j int) bool { return creatives[i].ID < creatives[j].ID })
+	}
+
+	locations := make(map[string]*time.Location)
+	for _, campaign := range data.campaigns {
+		if campaign.Status != models.StatusActive {
+			continue
+		}
+
+		location, ok := locations[campaign.TimeZone]
+		if !ok {
+			var err error
+			if location, err = campaign.Location(); err != nil {
+				slog.Warn("Skipping campaign with invalid time zone", "campaign", campaign.ID, "error", err)
+				continue
+			}
+			locations[campaign.TimeZone] = location
+		}
+
+		sched, err := compileSchedule(campaign, location)
+		if err != nil {
+			slog.Warn("Skipping campaign with invalid schedule", "campaign", campaign.ID, "error", err)
+			continue
+		}
+		compiled := &indexedCampaign{Campaign: campaign, location: location, schedule: sched, creatives: creativesByCampaign[campaign.ID]}
+
+		expr, hasExpr := expressionByCampaign[campaign.ID]
+		arms := armRules(campaign)
+		if hasExpr || !indexable(rulesByCampaign[campaign.ID]) || len(arms) > 0 {
+			var extra []models.Expression
+			if hasExpr {
+				extra = append(extra, expr)
+			}
+
+			evaluated, err := compileEvaluated(rulesByCampaign[campaign.ID], extra, arms)
+			if err != nil {
+				slog.Warn("Skipping campaign with invalid targeting", "campaign", campaign.ID, "error", err)
+				continue
+			}
+
+			evaluated.pos = idx.add(compiled)
+			idx.evaluated = append(idx.evaluated, evaluated)
+			continue
+		}
+
+		pos := idx.add(compiled)
+
+		required := 0
+		for dim, rule := range rulesByCampaign[campaign.ID] {
+			target := idx.exclude[dim]
+			if rule.RuleType == models.Include {
+				target = idx.include[dim]
+				required++
+			}
+			for _, value := range normalizedValues(rule.Values) {
+				target[value] = append(target[value], pos)
+			}
+		}
+
+		idx.required[pos] = required
+		if required == 0 {
+			idx.unconstrained = append(idx.unconstrained, pos)
+		}
+	}
+
+	return idx
+}
+
+// add appends a campaign and returns its position. Campaigns evaluated
+// through an expression keep a required count of -1.
+func (idx *campaignIndex) add(campaign *indexedCampaign) int {
+	idx.campaigns = append(idx.campaigns, campaign)
+	idx.required = append(idx.required, -1)
+	idx.byID[campaign.ID] = len(idx.campaigns) - 1
+	return len(idx.campaigns) - 1
+}
+
+// campaign returns the active campaign with the ID.
+func (idx *campaignIndex) campaign(id string) (*indexedCampaign, bool) {
+	pos, ok := idx.byID[id]
+	if !ok {
+		return nil, false
+	}
+	return idx.campaigns[pos], true
+}
+
+// hasCreative reports whether an active campaign has the creative.
+func (idx *campaignIndex) hasCreative(campaignID, creativeID string) bool {
+	campaign, ok := idx.campaign(campaignID)
+	if !ok {
+		return false
+	}
+	for _, c := range campaign.creatives {
+		if c.ID == creativeID {
+			return true
+		}
+	}
+	return false
+}
+
+// compileEvaluated compiles the targeting of a campaign evaluated against
+// every request: its rules, by dimension, and extra expressions, and the
+// same with the rules of each experiment arm in arms replacing the
+// campaign's for their dimensions.
+func compileEvaluated(rules map[models.DimensionType]models.TargetingRule, extra []models.Expression, arms map[string][]models.TargetingRule) (evaluatedCampaign, error) {
+	evaluated := evaluatedCampaign{arms: make(map[string]*compiledExpr, len(arms))}
+	var err error
+	if evaluated.expr, err = compileTargeting(rules, extra); err != nil {
+		return evaluated, err
+	}
+
+	for armID, armRules := range arms {
+		merged := maps.Clone(rules)
+		if merged == nil {
+			merged = make(map[models.DimensionType]models.TargetingRule, len(armRules))
+		}
+		for _, rule := range armRules {
+			merged[rule.DimensionType] = rule
+		}
+		if evaluated.arms[armID], err = compileTargeting(merged, extra); err != nil {
+			return evaluated, fmt.Errorf("arm %s: %w", armID, err)
+		}
+	}
+	return evaluated, nil
+}
+
+// compileTargeting compiles a campaign's rules, by dimension, and extra
+// expressions into one expression that must all hold.
+func compileTargeting(rules map[models.DimensionType]models.TargetingRule, extra []models.Expression) (*compiledExpr, error) {
+	list := make([]models.TargetingRule, 0, len(rules))
+	for _, rule := range rules {
+		list = append(list, rule)
+	}
+	combined := models.RulesExpression(list)
+	combined.And = append(combined.And, extra...)
+	return compileExpression(combined)
+}
+
+// armRules returns the rules of the campaign's experiment arms that replace
+// some of its rules, by arm ID.
+func armRules(campaign models.Campaign) map[string][]models.TargetingRule {
+	if campaign.Experiment == nil {
+		return nil
+	}
+	arms := make(map[string][]models.TargetingRule)
+	for _, arm := range campaign.Experiment.Arms {
+		for _, rule := range arm.Rules {
+			if !rule.DimensionType.Valid() {
+				continue
+			}
+			rule.CampaignID = campaign.ID
+			arms[arm.ID] = append(arms[arm.ID], rule)
+		}
+	}
+	return arms
+}
+
+// arm returns the experiment arm the device is in, or nil when it is in
+// none or the campaign runs no experiment.
+func (c *indexedCampaign) arm(deviceID string) *models.Arm {
+	return c.Experiment.Assign(deviceID)
+}
+
+// indexable reports whether rules only use exact matches on dimensions the
+// inverted maps cover.
+func indexable(rules map[models.DimensionType]models.TargetingRule) bool {
+	for dim, rule := range rules {
+		if !indexedDimensionSet[dim] || rule.MatchType.OrDefault() != models.MatchExact {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the campaigns whose rules accept req and that are scheduled
+// to run at now, in repository order.
+func (idx *campaignIndex) match(req models.DeliveryRequest, now time.Time) []*indexedCampaign {
+	hits := make(map[int]int)
+	excluded := make(map[int]bool)
+
+	for _, dim := range indexedDimensions {
+		value := strings.ToLower(requestValue(req, dim))
+		for _, pos := range idx.include[dim][value] {
+			hits[pos]++
+		}
+		for _, pos := range idx.exclude[dim][value] {
+			excluded[pos] = true
+		}
+	}
+
+	var positions []int
+	for _, pos := range idx.unconstrained {
+		if !excluded[pos] {
+			positions = append(positions, pos)
+		}
+	}
+	for pos, count := range hits {
+		if count == idx.required[pos] && !excluded[pos] {
+			positions = append(positions, pos)
+		}
+	}
+	for _, campaign := range idx.evaluated {
+		expr := campaign.expr
+		if arm := idx.campaigns[campaign.pos].arm(req.DeviceID); arm != nil && campaign.arms[arm.ID] != nil {
+			expr = campaign.arms[arm.ID]
+		}
+		if expr.eval(req) {
+			positions = append(positions, campaign.pos)
+		}
+	}
+	sort.Ints(positions)
+
+	matched := make([]*indexedCampaign, 0, len(positions))
+	for _, pos := range positions {
+		if idx.campaigns[pos].schedule.active(now) {
+			matched = append(matched, idx.campaigns[pos])
+		}
+	}
+	return matched
+}
+
+func requestValue(req models.DeliveryRequest, dim models.DimensionType) string {
+	switch dim {
+	case models.DimensionApp:
+		return req.App
+	case models.DimensionCountry:
+		return req.Country
+	case models.DimensionOS:
+		return req.OS
+	case models.DimensionOSVersion:
+		return req.OSVersion
+	case models.DimensionAppVersion:
+		return req.AppVersion
+	default:
+		return ""
+	}
+}
+
+// normalizedValues lowercases and deduplicates rule values so a campaign is
+// counted at most once per dimension.
+func normalizedValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		v := strings.ToLower(value)
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		normalized = append(normalized, v)
+	}
+	return normalized
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+func newTestRepository(t *testing.T) *repository.MemoryRepository {
+	return newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "spotify", Name: "Spotify", ImageURL: "https://somelink", CTA: "Download", Status: models.StatusActive},
+			{ID: "duolingo", Name: "Duolingo", ImageURL: "https://somelink2", CTA: "Install", Status: models.StatusActive},
+			{ID: "subwaysurfer", Name: "Subway Surfer", ImageURL: "https://somelink3", CTA: "Play", Status: models.StatusActive},
+			{ID: "paused", Name: "Paused", ImageURL: "https://somelink4", CTA: "Open", Status: models.StatusInactive},
+		},
+		[]models.TargetingRule{
+			{CampaignID: "spotify", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"US", "Canada"}},
+			{CampaignID: "duolingo", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"Android", "iOS"}},
+			{CampaignID: "duolingo", DimensionType: models.DimensionCountry, RuleType: models.Exclude, Values: []string{"US"}},
+			{CampaignID: "subwaysurfer", DimensionType: models.DimensionOS, RuleType: models.Include, Values: []string{"Android"}},
+			{CampaignID: "subwaysurfer", DimensionType: models.DimensionApp, RuleType: models.Include, Values: []string{"com.gametion.ludokinggame"}},
+		},
+	)
+}
+
+func TestIndexMatching(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	repo.SaveCampaign(ctx, models.Campaign{ID: "everywhere", Status: models.StatusActive})
+	svc := NewTargetingService(repo)
+
+	if _, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", OS: "b", Country: "c"}); err != ErrNotReady {
+		t.Fatalf("Expected ErrNotReady before first refresh, got %v", err)
+	}
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		request     models.DeliveryRequest
+		expectedIDs []string
+	}{
+		{
+			name:        "User in US on Android",
+			request:     models.DeliveryRequest{App: "com.example.app", Country: "US", OS: "Android"},
+			expectedIDs: []string{"everywhere", "spotify"},
+		},
+		{
+			name:        "User in Germany on iOS",
+			request:     models.DeliveryRequest{App: "com.example.app", Country: "DE", OS: "ios"},
+			expectedIDs: []string{"duolingo", "everywhere"},
+		},
+		{
+			name:        "Ludo King on Android in Canada",
+			request:     models.DeliveryRequest{App: "com.gametion.ludokinggame", Country: "canada", OS: "ANDROID"},
+			expectedIDs: []string{"duolingo", "everywhere", "spotify", "subwaysurfer"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			campaigns, err := svc.GetMatchingCampaigns(ctx, tc.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(campaigns) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %v but got %v", tc.expectedIDs, campaigns)
+			}
+			for i, id := range tc.expectedIDs {
+				if campaigns[i].CID != id {
+					t.Errorf("Expected campaign ID %s at %d but got %s", id, i, campaigns[i].CID)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshSwapsIndex(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	req := models.DeliveryRequest{App: "com.example.app", Country: "FR", OS: "web"}
+	if campaigns, _ := svc.GetMatchingCampaigns(ctx, req); len(campaigns) != 0 {
+		t.Fatalf("Expected no campaigns but got %v", campaigns)
+	}
+
+	repo.SaveCampaign(ctx, models.Campaign{ID: "web", Status: models.StatusActive})
+	if campaigns, _ := svc.GetMatchingCampaigns(ctx, req); len(campaigns) != 0 {
+		t.Fatalf("Expected the old index to be used until refresh, got %v", campaigns)
+	}
+
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if campaigns, _ := svc.GetMatchingCampaigns(ctx, req); len(campaigns) != 1 || campaigns[0].CID != "web" {
+		t.Fatalf("Expected the web campaign after refresh, got %v", campaigns)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync/atomic"
+
+	"targeting-engine/internal/models"
+)
+
+// Ranking strategies, as named in the configuration.
+const (
+	RankByPriority = "priority"
+	RankWeighted   = "weighted"
+	RankRoundRobin = "round_robin"
+)
+
+// Ranker orders the campaigns of a delivery, best first.
+type Ranker interface {
+	// Rank reorders campaigns in place.
+	Rank(campaigns []*models.Campaign)
+}
+
+// NewRanker returns the ranking strategy called name.
+func NewRanker(name string) (Ranker, error) {
+	switch name {
+	case RankByPriority:
+		return PriorityRanker{}, nil
+	case RankWeighted:
+		return &WeightedRanker{}, nil
+	case RankRoundRobin:
+		return &RoundRobinRanker{}, nil
+	}
+	return nil, fmt.Errorf("unknown ranking %q", name)
+}
+
+// WithRanker sets how delivered campaigns are ordered. The default is
+// PriorityRanker.
+func WithRanker(r Ranker) Option {
+	return func(s *TargetingService) {
+		s.ranker = r
+	}
+}
+
+// PriorityRanker orders campaigns by descending priority, then by ID, so the
+// same campaigns always come back in the same order.
+type PriorityRanker struct{}
+
+func (PriorityRanker) Rank(campaigns []*models.Campaign) {
+	sort.Slice(campaigns, func(i, j int) bool {
+		if campaigns[i].Priority != campaigns[j].Priority {
+			return campaigns[i].Priority > campaigns[j].Priority
+		}
+		return campaigns[i].ID < campaigns[j].ID
+	})
+}
+
+// WeightedRanker orders campaigns by descending priority and shuffles the
+// campaigns of the same priority, each coming first in proportion to its
+// weight.
+type WeightedRanker struct {
+	// Random returns numbers in [0, 1); rand.Float64 when nil.
+	Random func() float64
+}
+
+func (r *WeightedRanker) Rank(campaigns []*models.Campaign) {
+	random := r.Random
+	if random == nil {
+		random = rand.Float64
+	}
+
+	PriorityRanker{}.Rank(campaigns)
+	forEachTier(campaigns, func(tier []*models.Campaign) {
+		// Efraimidis-Spirakis: sorting by -ln(u)/weight draws campaigns
+		// without replacement in proportion to their weights.
+		keys := make(map[*models.Campaign]float64, len(tier))
+		for _, c := range tier {
+			keys[c] = -math.Log(1-random()) / float64(c.RankWeight())
+		}
+		sort.SliceStable(tier, func(i, j int) bool { return keys[tier[i]] < keys[tier[j]] })
+	})
+}
+
+// RoundRobinRanker orders campaigns by descending priority and rotates the
+// campaigns of the same priority, so each comes first in turn.
+type RoundRobinRanker struct {
+	next atomic.Uint64
+}
+
+func (r *RoundRobinRanker) Rank(campaigns []*models.Campaign) {
+	PriorityRanker{}.Rank(campaigns)
+	turn := r.next.Add(1) - 1
+	forEachTier(campaigns, func(tier []*models.Campaign) {
+		shift := int(turn % uint64(len(tier)))
+		rotated := append(append([]*models.Campaign{}, tier[shift:]...), tier[:shift]...)
+		copy(tier, rotated)
+	})
+}
+
+// forEachTier calls fn with every run of campaigns of the same priority in
+// campaigns, which must be sorted by priority.
+func forEachTier(campaigns []*models.Campaign, fn func(tier []*models.Campaign)) {
+	for start := 0; start < len(campaigns); {
+		end := start + 1
+		for end < len(campaigns) && campaigns[end].Priority == campaigns[start].Priority {
+			end++
+		}
+		fn(campaigns[start:end])
+		start = end
+	}
+}
+
+// rank orders campaigns with the service's ranker.
+func (s *TargetingService) rank(campaigns []*indexedCampaign) []*indexedCampaign {
+	byCampaign := make(map[*models.Campaign]*indexedCampaign, len(campaigns))
+	ranked := make([]*models.Campaign, len(campaigns))
+	for i, campaign := range campaigns {
+		ranked[i] = &campaign.Campaign
+		byCampaign[ranked[i]] = campaign
+	}
+	s.ranker.Rank(ranked)
+
+	result := make([]*indexedCampaign, len(ranked))
+	for i, c := range ranked {
+		result[i] = byCampaign[c]
+	}
+	return result
+}
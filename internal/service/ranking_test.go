@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"targeting-engine/internal/models"
+)
+
+func rankedIDs(r Ranker, campaigns []models.Campaign) []string {
+	ranked := make([]*models.Campaign, len(campaigns))
+	for i := range campaigns {
+		ranked[i] = &campaigns[i]
+	}
+	r.Rank(ranked)
+
+	ids := make([]string, len(ranked))
+	for i, c := range ranked {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestRankers(t *testing.T) {
+	campaigns := []models.Campaign{
+		{ID: "c", Priority: 1},
+		{ID: "low", Priority: -1},
+		{ID: "a", Priority: 1},
+		{ID: "top", Priority: 5},
+		{ID: "b", Priority: 1},
+	}
+
+	if got, want := rankedIDs(PriorityRanker{}, campaigns), []string{"top", "a", "b", "c", "low"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected priority order %v but got %v", want, got)
+	}
+
+	roundRobin := &RoundRobinRanker{}
+	for _, want := range [][]string{
+		{"top", "a", "b", "c", "low"},
+		{"top", "b", "c", "a", "low"},
+		{"top", "c", "a", "b", "low"},
+		{"top", "a", "b", "c", "low"},
+	} {
+		if got := rankedIDs(roundRobin, campaigns); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected round robin order %v but got %v", want, got)
+		}
+	}
+}
+
+func TestWeightedRanker(t *testing.T) {
+	campaigns := []models.Campaign{
+		{ID: "heavy", Weight: 3},
+		{ID: "light"},
+		{ID: "urgent", Priority: 1, Weight: 1},
+	}
+	random := rand.New(rand.NewSource(1))
+	ranker := &WeightedRanker{Random: random.Float64}
+
+	first := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		ids := rankedIDs(ranker, campaigns)
+		if ids[0] != "urgent" {
+			t.Fatalf("Expected the higher priority first but got %v", ids)
+		}
+		first[ids[1]]++
+	}
+	// heavy should come first 3 times in 4.
+	if share := float64(first["heavy"]) / 4000; share < 0.7 || share > 0.8 {
+		t.Errorf("Expected heavy first about 75%% of the time but got %.2f", share)
+	}
+}
+
+func TestDeliveryLimit(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "first", Status: models.StatusActive, Priority: 2, DailyImpressionBudget: 10},
+			{ID: "second", Status: models.StatusActive, Priority: 1, DailyImpressionBudget: 1},
+		},
+		nil,
+	)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	req := models.DeliveryRequest{App: "a", Country: "US", OS: "iOS", Limit: 1}
+	for i := 0; i < 2; i++ {
+		campaigns, err := svc.GetMatchingCampaigns(ctx, req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(campaigns) != 1 || campaigns[0].CID != "first" {
+			t.Fatalf("Expected only the first campaign but got %v", campaigns)
+		}
+	}
+
+	// The second campaign was never delivered, so its budget is untouched.
+	req.Limit = 0
+	campaigns, _ := svc.GetMatchingCampaigns(ctx, req)
+	if len(campaigns) != 2 {
+		t.Errorf("Expected both campaigns without a limit but got %v", campaigns)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+// schedule is a campaign's compiled flight dates and dayparts. A nil
+// schedule is always active.
+type schedule struct {
+	start, end *time.Time
+	location   *time.Location
+	// windows holds the [start, end) minute ranges for each weekday; it is
+	// nil when the campaign runs all day.
+	windows *[7][][2]int
+}
+
+func compileSchedule(campaign models.Campaign, location *time.Location) (*schedule, error) {
+	if campaign.StartAt == nil && campaign.EndAt == nil && len(campaign.Dayparts) == 0 {
+		return nil, nil
+	}
+
+	sched := &schedule{start: campaign.StartAt, end: campaign.EndAt, location: location}
+	if len(campaign.Dayparts) == 0 {
+		return sched, nil
+	}
+
+	sched.windows = new([7][][2]int)
+	for _, daypart := range campaign.Dayparts {
+		start, err := models.ParseClock(daypart.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := models.ParseClock(daypart.End)
+		if err != nil {
+			return nil, err
+		}
+
+		days := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+		if len(daypart.Days) > 0 {
+			days = days[:0]
+			for _, day := range daypart.Days {
+				weekday, err := models.ParseWeekday(day)
+				if err != nil {
+					return nil, err
+				}
+				days = append(days, weekday)
+			}
+		}
+		for _, day := range days {
+			sched.windows[day] = append(sched.windows[day], [2]int{start, end})
+		}
+	}
+	return sched, nil
+}
+
+// active reports whether the campaign may deliver at now.
+func (s *schedule) active(now time.Time) bool {
+	return s.inactiveReason(now) == ""
+}
+
+// inactiveReason tells why the campaign may not deliver at now, or returns
+// "" when it may.
+func (s *schedule) inactiveReason(now time.Time) string {
+	if s == nil {
+		return ""
+	}
+	if s.start != nil && now.Before(*s.start) {
+		return "starts at " + s.start.Format(time.RFC3339)
+	}
+	if s.end != nil && !now.Before(*s.end) {
+		return "ended at " + s.end.Format(time.RFC3339)
+	}
+	if s.windows == nil {
+		return ""
+	}
+
+	local := now.In(s.location)
+	minute := local.Hour()*60 + local.Minute()
+	for _, window := range s.windows[local.Weekday()] {
+		if minute >= window[0] && minute < window[1] {
+			return ""
+		}
+	}
+	return "outside its dayparts at " + local.Format("Mon 15:04") + " " + s.location.String()
+}
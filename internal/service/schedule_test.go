@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/models"
+)
+
+func TestScheduledCampaigns(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := newMemoryRepository(t,
+		[]models.Campaign{
+			{ID: "june", Status: models.StatusActive, StartAt: &start, EndAt: &end},
+			{
+				ID:       "office-hours",
+				Status:   models.StatusActive,
+				TimeZone: "America/New_York",
+				Dayparts: models.Dayparts{{Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "09:00", End: "17:00"}},
+			},
+			{
+				ID:       "late-night",
+				Status:   models.StatusActive,
+				Dayparts: models.Dayparts{{Start: "22:00", End: "24:00"}, {Start: "00:00", End: "02:00"}},
+			},
+		},
+		nil,
+	)
+
+	var now time.Time
+	svc := NewTargetingService(repo, WithClock(func() time.Time { return now }))
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		expectedIDs []string
+	}{
+		{name: "Before the flight", now: time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)},
+		// Monday 10:00 in New York
+		{name: "Weekday morning in New York", now: time.Date(2024, 6, 3, 14, 0, 0, 0, time.UTC), expectedIDs: []string{"june", "office-hours"}},
+		// Monday 08:59 in New York
+		{name: "Before office hours", now: time.Date(2024, 6, 3, 12, 59, 0, 0, time.UTC), expectedIDs: []string{"june"}},
+		// Saturday 10:00 in New York
+		{name: "Weekend", now: time.Date(2024, 6, 8, 14, 0, 0, 0, time.UTC), expectedIDs: []string{"june"}},
+		{name: "Overnight window", now: time.Date(2024, 6, 8, 1, 30, 0, 0, time.UTC), expectedIDs: []string{"june", "late-night"}},
+		{name: "End of the flight is exclusive", now: end, expectedIDs: []string{"late-night"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now = tc.now
+			campaigns, err := svc.GetMatchingCampaigns(ctx, models.DeliveryRequest{App: "a", Country: "US", OS: "iOS"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(campaigns) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %v but got %v", tc.expectedIDs, campaigns)
+			}
+			for i, id := range tc.expectedIDs {
+				if campaigns[i].CID != id {
+					t.Errorf("Expected campaign ID %s at %d but got %s", id, i, campaigns[i].CID)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleValidation(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	valid := models.Campaign{ID: "c", Name: "c", ImageURL: "i", CTA: "c", Status: models.StatusActive}
+
+	invalid := []func(c *models.Campaign){
+		func(c *models.Campaign) { c.StartAt, c.EndAt = &start, &start },
+		func(c *models.Campaign) { c.TimeZone = "Mars/Olympus" },
+		func(c *models.Campaign) {
+			c.Dayparts = models.Dayparts{{Days: []string{"MONDAY"}, Start: "09:00", End: "10:00"}}
+		},
+		func(c *models.Campaign) { c.Dayparts = models.Dayparts{{Start: "9:00", End: "10:00"}} },
+		func(c *models.Campaign) { c.Dayparts = models.Dayparts{{Start: "22:00", End: "02:00"}} },
+		func(c *models.Campaign) { c.Dayparts = models.Dayparts{{Start: "00:00", End: "24:30"}} },
+	}
+	for i, mutate := range invalid {
+		c := valid
+		mutate(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected invalid schedule %d to be rejected", i)
+		}
+	}
+}
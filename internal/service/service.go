@@ -3,9 +3,49 @@ package service
 import (
 	"context"
 
+	"targeting-engine/internal/bulk"
 	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
 )
 
 type Service interface {
 	GetMatchingCampaigns(ctx context.Context, req models.DeliveryRequest) ([]models.CampaignResponse, error)
 }
+
+// ExplainService tells why campaigns would or wouldn't be delivered.
+type ExplainService interface {
+	Explain(ctx context.Context, req models.DeliveryRequest) (*models.Explanation, error)
+}
+
+// ClickService counts clicks on delivered creatives.
+type ClickService interface {
+	RecordClick(ctx context.Context, campaignID, creativeID string) error
+}
+
+type AdminService interface {
+	CreateCampaign(ctx context.Context, campaign models.Campaign) (*models.Campaign, error)
+	GetCampaign(ctx context.Context, id string) (*models.Campaign, error)
+	ListCampaigns(ctx context.Context, filter repository.CampaignFilter) ([]models.Campaign, error)
+	UpdateCampaign(ctx context.Context, campaign models.Campaign) (*models.Campaign, error)
+	PatchCampaign(ctx context.Context, id string, patch models.CampaignPatch) (*models.Campaign, error)
+	DeleteCampaign(ctx context.Context, id string) error
+
+	ListRules(ctx context.Context, campaignID string) ([]models.TargetingRule, error)
+	GetRule(ctx context.Context, campaignID string, dimension models.DimensionType) (*models.TargetingRule, error)
+	CreateRule(ctx context.Context, rule models.TargetingRule) (*models.TargetingRule, error)
+	UpdateRule(ctx context.Context, rule models.TargetingRule) (*models.TargetingRule, error)
+	DeleteRule(ctx context.Context, campaignID string, dimension models.DimensionType) error
+
+	GetExpression(ctx context.Context, campaignID string) (*models.TargetingExpression, error)
+	SetExpression(ctx context.Context, expr models.TargetingExpression) (*models.TargetingExpression, error)
+	DeleteExpression(ctx context.Context, campaignID string) error
+
+	ListCreatives(ctx context.Context, campaignID string) ([]models.Creative, error)
+	GetCreative(ctx context.Context, campaignID, id string) (*models.Creative, error)
+	CreateCreative(ctx context.Context, creative models.Creative) (*models.Creative, error)
+	UpdateCreative(ctx context.Context, creative models.Creative) (*models.Creative, error)
+	DeleteCreative(ctx context.Context, campaignID, id string) error
+
+	Export(ctx context.Context) (*bulk.Data, error)
+	Import(ctx context.Context, data *bulk.Data, opts ImportOptions) (*bulk.Plan, error)
+}
@@ -3,106 +3,368 @@ package service
 import (
 	"context"
 	"errors"
-	"strings"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"targeting-engine/internal/auction"
+	"targeting-engine/internal/budget"
+	"targeting-engine/internal/creative"
+	"targeting-engine/internal/frequency"
+	"targeting-engine/internal/metrics"
 	"targeting-engine/internal/models"
 	"targeting-engine/internal/repository"
 )
 
+var tracer = otel.Tracer("targeting-engine/internal/service")
+
 var (
 	ErrInvalidRequest = errors.New("invalid request: missing required parameters")
+	ErrNotReady       = errors.New("targeting data not loaded yet")
 )
 
 type TargetingService struct {
-	repo repository.Repository
+	repo  repository.Repository
+	index atomic.Pointer[campaignIndex]
+	now   func() time.Time
+
+	frequency frequency.Store
+	budget    budget.Store
+	creatives creative.Store
+	ranker    Ranker
+	auction   atomic.Pointer[auction.Auction]
+	metrics   *metrics.Metrics
+
+	// syncedAt is when the index was last known to match the repository,
+	// in Unix nanoseconds.
+	syncedAt atomic.Int64
+
+	// intervals carries refresh intervals set while Watch or
+	// RefreshPeriodically is running.
+	intervals chan time.Duration
+
+	// mu serializes index rebuilds. data is what the current index was
+	// built from, kept so single campaigns can be reloaded.
+	mu   sync.Mutex
+	data targetingData
 }
 
-func NewTargetingService(repo repository.Repository) *TargetingService {
-	return &TargetingService{
-		repo: repo,
+// Option customizes a TargetingService.
+type Option func(*TargetingService)
+
+// WithClock makes the service read the current time from now, which decides
+// whether campaigns are within their flight dates and dayparts.
+func WithClock(now func() time.Time) Option {
+	return func(s *TargetingService) {
+		s.now = now
 	}
 }
 
-func (s *TargetingService) GetMatchingCampaigns(ctx context.Context, req models.DeliveryRequest) ([]models.CampaignResponse, error) {
-	if req.App == "" || req.OS == "" || req.Country == "" {
-		return nil, ErrInvalidRequest
+// WithMetrics records cache refresh statistics in m.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *TargetingService) {
+		s.metrics = m
 	}
+}
 
+func NewTargetingService(repo repository.Repository, opts ...Option) *TargetingService {
+	s := &TargetingService{
+		repo:      repo,
+		now:       time.Now,
+		intervals: make(chan time.Duration, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.frequency == nil {
+		s.frequency = frequency.NewMemoryStore(defaultFrequencyDevices)
+	}
+	if s.budget == nil {
+		s.budget = budget.NewMemoryStore()
+	}
+	if s.creatives == nil {
+		s.creatives = creative.NewMemoryStore()
+	}
+	if s.ranker == nil {
+		s.ranker = PriorityRanker{}
+	}
+	return s
+}
+
+// Refresh loads every campaign and targeting rule from the repository,
+// compiles them into a new index and swaps it in for subsequent requests.
+func (s *TargetingService) Refresh(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "TargetingService.Refresh")
+	defer span.End()
+
+	start := time.Now()
+	count, err := s.refresh(ctx)
+	s.metrics.ObserveRefresh(metrics.RefreshFull, time.Since(start), count, err)
+	if err == nil {
+		s.markSynced()
+	}
+	recordSpan(span, err, attribute.Int("campaigns", count))
+	return err
+}
+
+func (s *TargetingService) refresh(ctx context.Context) (int, error) {
 	campaigns, err := s.repo.GetCampaigns(ctx)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	rules, err := s.repo.GetTargetingRules(ctx)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+
+	expressions, err := s.repo.GetTargetingExpressions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	creatives, err := s.repo.GetCreatives(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data = targetingData{
+		campaigns:   campaigns,
+		rules:       rules,
+		expressions: expressions,
+		creatives:   creatives,
+	}
+	idx := buildIndex(s.data, time.Now())
+	s.index.Store(idx)
+	return len(idx.campaigns), nil
+}
+
+// reloadCampaigns reads the given campaigns and their rules from the
+// repository and rebuilds the index with them, leaving every other campaign
+// as it was.
+func (s *TargetingService) reloadCampaigns(ctx context.Context, ids map[string]bool) error {
+	ctx, span := tracer.Start(ctx, "TargetingService.reloadCampaigns",
+		trace.WithAttributes(attribute.Int("changed", len(ids))))
+	defer span.End()
+
+	start := time.Now()
+	count, err := s.reload(ctx, ids)
+	s.metrics.ObserveRefresh(metrics.RefreshIncremental, time.Since(start), count, err)
+	if err == nil {
+		s.markSynced()
 	}
+	recordSpan(span, err, attribute.Int("campaigns", count))
+	return err
+}
+
+func (s *TargetingService) reload(ctx context.Context, ids map[string]bool) (int, error) {
+	changed := make(map[string]*models.Campaign, len(ids))
+	var changedRules []models.TargetingRule
+	var changedExpressions []models.TargetingExpression
+	var changedCreatives []models.Creative
+	for id := range ids {
+		campaign, err := s.repo.GetCampaignByID(ctx, id)
+		if err != nil && !errors.Is(err, repository.ErrCampaignNotFound) {
+			return 0, err
+		}
+		changed[id] = campaign
+
+		rules, err := s.repo.GetTargetingRulesByCampaignID(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		changedRules = append(changedRules, rules...)
 
-	rulesByCampaign := make(map[string]map[models.DimensionType]models.TargetingRule)
+		expr, err := s.repo.GetTargetingExpressionByCampaignID(ctx, id)
+		if err != nil && !errors.Is(err, repository.ErrExpressionNotFound) {
+			return 0, err
+		}
+		if expr != nil {
+			changedExpressions = append(changedExpressions, *expr)
+		}
 
-	for _, rule := range rules {
-		if _, ok := rulesByCampaign[rule.CampaignID]; !ok {
-			rulesByCampaign[rule.CampaignID] = make(map[models.DimensionType]models.TargetingRule)
+		creatives, err := s.repo.GetCreativesByCampaignID(ctx, id)
+		if err != nil {
+			return 0, err
 		}
-		rulesByCampaign[rule.CampaignID][rule.DimensionType] = rule
+		changedCreatives = append(changedCreatives, creatives...)
 	}
 
-	var matchingAds []models.CampaignResponse
-	for _, campaign := range campaigns {
-		// Skip inactive ads // but we have picked only actives
-		if campaign.Status != models.StatusActive {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaigns := make([]models.Campaign, 0, len(s.data.campaigns)+len(changed))
+	for _, campaign := range s.data.campaigns {
+		if _, ok := changed[campaign.ID]; !ok {
+			campaigns = append(campaigns, campaign)
 			continue
 		}
+		if c := changed[campaign.ID]; c != nil {
+			campaigns = append(campaigns, *c)
+			delete(changed, campaign.ID)
+		}
+	}
+	added := make([]string, 0, len(changed))
+	for id, c := range changed {
+		if c != nil {
+			added = append(added, id)
+		}
+	}
+	sort.Strings(added)
+	for _, id := range added {
+		campaigns = append(campaigns, *changed[id])
+	}
 
-		if s.campaignMatchesRules(campaign.ID, req, rulesByCampaign) {
-			matchingAds = append(matchingAds, campaign.ToCampaignResponse())
+	rules := make([]models.TargetingRule, 0, len(s.data.rules)+len(changedRules))
+	for _, rule := range s.data.rules {
+		if !ids[rule.CampaignID] {
+			rules = append(rules, rule)
 		}
 	}
+	rules = append(rules, changedRules...)
 
-	return matchingAds, nil
+	expressions := make([]models.TargetingExpression, 0, len(s.data.expressions)+len(changedExpressions))
+	for _, expr := range s.data.expressions {
+		if !ids[expr.CampaignID] {
+			expressions = append(expressions, expr)
+		}
+	}
+	expressions = append(expressions, changedExpressions...)
+
+	creatives := make([]models.Creative, 0, len(s.data.creatives)+len(changedCreatives))
+	for _, creative := range s.data.creatives {
+		if !ids[creative.CampaignID] {
+			creatives = append(creatives, creative)
+		}
+	}
+	creatives = append(creatives, changedCreatives...)
+
+	s.data = targetingData{
+		campaigns:   campaigns,
+		rules:       rules,
+		expressions: expressions,
+		creatives:   creatives,
+	}
+	idx := buildIndex(s.data, time.Now())
+	s.index.Store(idx)
+	return len(idx.campaigns), nil
 }
 
-func (s *TargetingService) campaignMatchesRules(campaignID string, req models.DeliveryRequest, rulesByCampaign map[string]map[models.DimensionType]models.TargetingRule) bool {
-	rules, exists := rulesByCampaign[campaignID]
-	if !exists {
-		return true
+// Staleness returns how long ago the targeting data was last known to be in
+// sync with the repository, or ErrNotReady if it was never loaded.
+func (s *TargetingService) Staleness() (time.Duration, error) {
+	if s.index.Load() == nil {
+		return 0, ErrNotReady
 	}
+	return time.Since(time.Unix(0, s.syncedAt.Load())), nil
+}
+
+func (s *TargetingService) markSynced() {
+	s.syncedAt.Store(time.Now().UnixNano())
+}
 
-	if appRule, exists := rules[models.DimensionApp]; exists {
-		if !s.matchesDimensionRule(req.App, appRule) {
-			return false
+// RefreshPeriodically calls Refresh every interval until ctx is done. A failed
+// refresh keeps the previous index in place.
+func (s *TargetingService) RefreshPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case interval := <-s.intervals:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			s.refreshLogged(ctx)
 		}
 	}
+}
 
-	if countryRule, exists := rules[models.DimensionCountry]; exists {
-		if !s.matchesDimensionRule(req.Country, countryRule) {
-			return false
+// SetRefreshInterval changes the interval of a running RefreshPeriodically,
+// or the fallback interval of a running Watch.
+func (s *TargetingService) SetRefreshInterval(interval time.Duration) {
+	for {
+		select {
+		case s.intervals <- interval:
+			return
+		default:
+		}
+		// Replace an interval that hasn't been picked up yet.
+		select {
+		case <-s.intervals:
+		default:
 		}
 	}
+}
 
-	if osRule, exists := rules[models.DimensionOS]; exists {
-		if !s.matchesDimensionRule(req.OS, osRule) {
-			return false
-		}
+func (s *TargetingService) GetMatchingCampaigns(ctx context.Context, req models.DeliveryRequest) ([]models.CampaignResponse, error) {
+	ctx, span := tracer.Start(ctx, "TargetingService.GetMatchingCampaigns", trace.WithAttributes(
+		attribute.String("app", req.App),
+		attribute.String("os", req.OS),
+		attribute.String("country", req.Country),
+	))
+	defer span.End()
+
+	if req.App == "" || req.OS == "" || req.Country == "" {
+		recordSpan(span, ErrInvalidRequest)
+		return nil, ErrInvalidRequest
 	}
 
-	return true
-}
+	idx := s.index.Load()
+	if idx == nil {
+		recordSpan(span, ErrNotReady)
+		return nil, ErrNotReady
+	}
 
-func (s *TargetingService) matchesDimensionRule(value string, rule models.TargetingRule) bool {
-	normalizedValue := strings.ToLower(value)
+	now := s.now()
+	campaigns := idx.match(req, now)
+	campaigns = filterCreatives(req, campaigns)
+	span.SetAttributes(attribute.Int("matched", len(campaigns)))
+	campaigns = s.filterFrequencyCaps(ctx, req.DeviceID, campaigns, now)
+	campaigns, reserved := s.reserveBudgets(ctx, campaigns, now)
+	campaigns = s.rank(campaigns)
+	campaigns, prices := s.runAuction(req, campaigns)
+	// Only what is delivered counts towards caps and budgets.
+	if req.Limit > 0 && len(campaigns) > req.Limit {
+		campaigns = campaigns[:req.Limit]
+	}
+	campaigns = s.recordExposures(ctx, req.DeviceID, campaigns, now)
+	s.settleBudgets(ctx, reserved, campaigns, prices)
 
-	valueInRules := false
-	for _, ruleValue := range rule.Values {
-		if strings.ToLower(ruleValue) == normalizedValue {
-			valueInRules = true
-			break
+	var matchingAds []models.CampaignResponse
+	for _, campaign := range campaigns {
+		ad := campaign.ToCampaignResponse()
+		if creative := s.chooseCreative(ctx, req, campaign); creative != nil {
+			setCreative(&ad, creative)
+		}
+		if arm := campaign.arm(req.DeviceID); arm != nil {
+			ad.Experiment, ad.Arm = campaign.Experiment.ID, arm.ID
 		}
+		if price, ok := prices[campaign.ID]; ok {
+			ad.Price = &price
+		}
+		matchingAds = append(matchingAds, ad)
 	}
+	span.SetAttributes(attribute.Int("delivered", len(matchingAds)))
 
-	if rule.RuleType == models.Include {
-		return valueInRules
-	} else {
-		return !valueInRules
+	return matchingAds, nil
+}
+
+// recordSpan records err, if any, and attrs on span.
+func recordSpan(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
 }
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"targeting-engine/internal/repository"
+)
+
+// changeBatchDelay is how long Watch collects change events before reloading,
+// so a campaign saved together with its rules is reloaded once.
+const changeBatchDelay = 50 * time.Millisecond
+
+// Watch keeps the index in sync with the change events pushed by notifier,
+// reloading only the campaigns that changed. Whenever events may have been
+// missed it reloads everything, and while the notifier is disconnected it
+// falls back to a full reload every fallbackInterval, which
+// SetRefreshInterval can change.
+func (s *TargetingService) Watch(ctx context.Context, notifier repository.Notifier, fallbackInterval time.Duration) error {
+	events, err := notifier.Listen(ctx)
+	if err != nil {
+		return err
+	}
+
+	fallback := time.NewTicker(fallbackInterval)
+	defer fallback.Stop()
+
+	pending := make(map[string]bool)
+	var flush <-chan time.Time
+	stale := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case ev, ok := <-events:
+			if !ok {
+				// The notifier gave up; poll from now on.
+				slog.Warn("Targeting change events stopped, falling back to full reloads")
+				events = nil
+				stale = true
+				continue
+			}
+			switch ev.Kind {
+			case repository.ChangeDisconnected:
+				stale = true
+			case repository.ChangeResync:
+				stale = s.refreshLogged(ctx) != nil
+				pending = make(map[string]bool)
+				flush = nil
+			case repository.ChangeRow:
+				pending[ev.CampaignID] = true
+				if flush == nil {
+					flush = time.After(changeBatchDelay)
+				}
+			}
+
+		case <-flush:
+			if err := s.reloadCampaigns(ctx, pending); err != nil && ctx.Err() == nil {
+				slog.Warn("Couldn't reload changed campaigns, falling back to a full reload", "error", err)
+				stale = true
+			}
+			pending = make(map[string]bool)
+			flush = nil
+
+		case interval := <-s.intervals:
+			fallback.Reset(interval)
+
+		case <-fallback.C:
+			if stale {
+				stale = s.refreshLogged(ctx) != nil
+			} else if len(pending) == 0 {
+				// Connected with nothing pending, so nothing was missed.
+				s.markSynced()
+			}
+		}
+	}
+}
+
+func (s *TargetingService) refreshLogged(ctx context.Context) error {
+	err := s.Refresh(ctx)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("Couldn't refresh targeting data", "error", err)
+	}
+	return err
+}
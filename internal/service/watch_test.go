@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"targeting-engine/internal/models"
+	"targeting-engine/internal/repository"
+)
+
+type mockNotifier struct {
+	events chan repository.ChangeEvent
+}
+
+func (n *mockNotifier) Listen(ctx context.Context) (<-chan repository.ChangeEvent, error) {
+	return n.events, nil
+}
+
+func waitForCampaigns(t *testing.T, svc *TargetingService, req models.DeliveryRequest, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		campaigns, err := svc.GetMatchingCampaigns(context.Background(), req)
+		if err == nil && len(campaigns) == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d campaigns but got %v (err %v)", expected, campaigns, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatchReloadsChangedCampaigns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newTestRepository(t)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	notifier := &mockNotifier{events: make(chan repository.ChangeEvent)}
+	go svc.Watch(ctx, notifier, time.Hour)
+
+	req := models.DeliveryRequest{App: "com.example.app", Country: "US", OS: "Android"}
+	waitForCampaigns(t, svc, req, 1)
+
+	// Narrow spotify down to Canada only.
+	repo.SaveTargetingRule(ctx, models.TargetingRule{CampaignID: "spotify", DimensionType: models.DimensionCountry, RuleType: models.Include, Values: []string{"Canada"}})
+	notifier.events <- repository.ChangeEvent{Kind: repository.ChangeRow, Table: "targeting_rules", Op: "UPDATE", CampaignID: "spotify"}
+	waitForCampaigns(t, svc, req, 0)
+
+	// A brand new campaign shows up without a full reload.
+	repo.SaveCampaign(ctx, models.Campaign{ID: "new", Status: models.StatusActive})
+	notifier.events <- repository.ChangeEvent{Kind: repository.ChangeRow, Table: "campaigns", Op: "INSERT", CampaignID: "new"}
+	waitForCampaigns(t, svc, req, 1)
+
+	// Deleting it removes it again.
+	repo.DeleteCampaign(ctx, "new")
+	notifier.events <- repository.ChangeEvent{Kind: repository.ChangeRow, Table: "campaigns", Op: "DELETE", CampaignID: "new"}
+	waitForCampaigns(t, svc, req, 0)
+}
+
+func TestWatchFallsBackToFullReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newTestRepository(t)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	notifier := &mockNotifier{events: make(chan repository.ChangeEvent)}
+	go svc.Watch(ctx, notifier, 10*time.Millisecond)
+
+	repo.SaveCampaign(ctx, models.Campaign{ID: "everywhere", Status: models.StatusActive})
+	notifier.events <- repository.ChangeEvent{Kind: repository.ChangeDisconnected}
+
+	req := models.DeliveryRequest{App: "com.example.app", Country: "FR", OS: "web"}
+	waitForCampaigns(t, svc, req, 1)
+}
+
+func TestWatchPollsAfterEventsClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newTestRepository(t)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	notifier := &mockNotifier{events: make(chan repository.ChangeEvent)}
+	go svc.Watch(ctx, notifier, 10*time.Millisecond)
+
+	repo.SaveCampaign(ctx, models.Campaign{ID: "everywhere", Status: models.StatusActive})
+	close(notifier.events)
+
+	req := models.DeliveryRequest{App: "com.example.app", Country: "FR", OS: "web"}
+	waitForCampaigns(t, svc, req, 1)
+}
+
+func TestSetRefreshInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newTestRepository(t)
+	svc := NewTargetingService(repo)
+	if err := svc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	repo.SaveCampaign(ctx, models.Campaign{ID: "everywhere", Status: models.StatusActive})
+	go svc.RefreshPeriodically(ctx, time.Hour)
+	svc.SetRefreshInterval(10 * time.Millisecond)
+
+	req := models.DeliveryRequest{App: "com.example.app", Country: "FR", OS: "web"}
+	waitForCampaigns(t, svc, req, 1)
+}
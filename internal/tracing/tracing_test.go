@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"targeting-engine/configs"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), configs.Tracing{Exporter: "file", File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "GET /v1/delivery")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Reading traces failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"Name":"GET /v1/delivery"`) || !strings.Contains(string(data), ServiceName) {
+		t.Errorf("Expected the span to be written to the file, got %s", data)
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), configs.Tracing{Exporter: "jaeger"}); err == nil {
+		t.Error("Expected an unknown exporter to be rejected")
+	}
+}
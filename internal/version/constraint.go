@@ -0,0 +1,155 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errEmptyConstraint = errors.New("empty version constraint")
+
+type operator int
+
+const (
+	opEQ operator = iota
+	opGT
+	opGTE
+	opLT
+	opLTE
+)
+
+type comparator struct {
+	op      operator
+	version Version
+}
+
+func (c comparator) check(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case opGT:
+		return cmp > 0
+	case opGTE:
+		return cmp >= 0
+	case opLT:
+		return cmp < 0
+	case opLTE:
+		return cmp <= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// Constraint is a version range. A version satisfies it when it satisfies
+// every comparator in it.
+type Constraint struct {
+	raw         string
+	comparators []comparator
+}
+
+// ParseConstraint reads a version range. Supported forms are:
+//
+//	4.2.0          exactly 4.2.0
+//	5, 5.x, 5.*    any 5.y.z
+//	*              any version
+//	>10, >=10      comparisons; partial versions are widened, so >5 and >5.x mean >=6.0.0
+//	<5, <=5.2      and <=5.2 means <5.3.0
+//	>=4.2.0 <6     several comparators separated by spaces or commas must all hold
+//	4.2.0 - 5.x    an inclusive range
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errEmptyConstraint
+	}
+
+	if lo, hi, ok := strings.Cut(s, " - "); ok {
+		from, err := parsePartial(lo)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartial(hi)
+		if err != nil {
+			return nil, err
+		}
+		c.comparators = append(c.comparators, comparator{op: opGTE, version: from.floor()})
+		c.comparators = append(c.comparators, upTo(to)...)
+		return c, nil
+	}
+
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		comparators, err := parseComparator(field)
+		if err != nil {
+			return nil, err
+		}
+		c.comparators = append(c.comparators, comparators...)
+	}
+	return c, nil
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	op, rest := "", s
+	for _, prefix := range []string{">=", "<=", "==", ">", "<", "="} {
+		if strings.HasPrefix(s, prefix) {
+			op, rest = prefix, s[len(prefix):]
+			break
+		}
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("invalid version constraint %q: missing version", s)
+	}
+
+	p, err := parsePartial(rest)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.parts) == 0 {
+		if op != "" && op != "=" && op != "==" {
+			return nil, fmt.Errorf("invalid version constraint %q: wildcard can't be compared", s)
+		}
+		return nil, nil
+	}
+
+	switch op {
+	case ">":
+		if p.wildcard() {
+			return []comparator{{op: opGTE, version: p.ceiling()}}, nil
+		}
+		return []comparator{{op: opGT, version: p.floor()}}, nil
+	case ">=":
+		return []comparator{{op: opGTE, version: p.floor()}}, nil
+	case "<":
+		return []comparator{{op: opLT, version: p.floor()}}, nil
+	case "<=":
+		return upTo(p), nil
+	default:
+		if p.wildcard() {
+			return []comparator{{op: opGTE, version: p.floor()}, {op: opLT, version: p.ceiling()}}, nil
+		}
+		return []comparator{{op: opEQ, version: p.floor()}}, nil
+	}
+}
+
+// upTo is the inclusive upper bound for p.
+func upTo(p partial) []comparator {
+	if len(p.parts) == 0 {
+		return nil
+	}
+	if p.wildcard() {
+		return []comparator{{op: opLT, version: p.ceiling()}}
+	}
+	return []comparator{{op: opLTE, version: p.floor()}}
+}
+
+// Check reports whether v satisfies the constraint.
+func (c *Constraint) Check(v Version) bool {
+	for _, comparator := range c.comparators {
+		if !comparator.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
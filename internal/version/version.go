@@ -0,0 +1,136 @@
+// Package version parses dotted version numbers such as OS and app versions
+// and matches them against semantic-version style range constraints.
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a MAJOR.MINOR.PATCH version with an optional pre-release tag.
+// Missing parts are zero, so "10" and "10.0.0" are the same version.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// Parse reads versions like "10", "4.2", "v4.2.0" or "5.0.1-beta.2". Build
+// metadata after a "+" is ignored.
+func Parse(s string) (Version, error) {
+	p, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if p.star {
+		return Version{}, fmt.Errorf("version %q must not contain wildcards", s)
+	}
+	return p.floor(), nil
+}
+
+// Compare returns -1, 0 or 1 when v is lower than, equal to or higher than o.
+// A pre-release sorts before the release it precedes.
+func (v Version) Compare(o Version) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	case v.Pre < o.Pre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// partial is a version where trailing parts may be missing or wildcards,
+// such as "5", "5.x" or "4.2.*". parts holds the numbers that were given and
+// star is set when a wildcard was spelled out.
+type partial struct {
+	parts []int
+	pre   string
+	star  bool
+}
+
+func parsePartial(s string) (partial, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "v"), "V")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var p partial
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, p.pre = s[:i], s[i+1:]
+		if p.pre == "" {
+			return partial{}, fmt.Errorf("invalid version %q: empty pre-release", raw)
+		}
+	}
+	if s == "" {
+		return partial{}, fmt.Errorf("invalid version %q", raw)
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return partial{}, fmt.Errorf("invalid version %q: more than three parts", raw)
+	}
+	for i, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			// Everything after a wildcard is a wildcard too.
+			for _, rest := range fields[i+1:] {
+				if rest != "x" && rest != "X" && rest != "*" {
+					return partial{}, fmt.Errorf("invalid version %q: number after wildcard", raw)
+				}
+			}
+			p.star = true
+			break
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return partial{}, fmt.Errorf("invalid version %q: %q is not a number", raw, field)
+		}
+		p.parts = append(p.parts, n)
+	}
+	if p.pre != "" && len(p.parts) != 3 {
+		return partial{}, fmt.Errorf("invalid version %q: pre-release needs a full version", raw)
+	}
+	return p, nil
+}
+
+func (p partial) wildcard() bool {
+	return len(p.parts) < 3
+}
+
+// floor is the lowest version p covers.
+func (p partial) floor() Version {
+	var parts [3]int
+	copy(parts[:], p.parts)
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2], Pre: p.pre}
+}
+
+// ceiling is the lowest version above everything p covers. It is only
+// meaningful when p has at least one number and a wildcard.
+func (p partial) ceiling() Version {
+	var parts [3]int
+	copy(parts[:], p.parts)
+	parts[len(p.parts)-1]++
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2]}
+}
@@ -0,0 +1,91 @@
+package version
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		invalid  bool
+	}{
+		{input: "10", expected: "10.0.0"},
+		{input: "4.2", expected: "4.2.0"},
+		{input: "v4.2.1", expected: "4.2.1"},
+		{input: "5.0.1-beta.2+build7", expected: "5.0.1-beta.2"},
+		{input: "", invalid: true},
+		{input: "5.x", invalid: true},
+		{input: "1.2.3.4", invalid: true},
+		{input: "ten", invalid: true},
+	}
+	for _, tc := range tests {
+		v, err := Parse(tc.input)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected but got %s", tc.input, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.input, err)
+			continue
+		}
+		if v.String() != tc.expected {
+			t.Errorf("Expected %q to parse as %s but got %s", tc.input, tc.expected, v)
+		}
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		misses     []string
+	}{
+		{constraint: ">=10", matches: []string{"10", "10.0.1", "14"}, misses: []string{"9", "9.9.9", "10.0.0-rc.1"}},
+		{constraint: ">10", matches: []string{"11"}, misses: []string{"10", "10.9.9"}},
+		{constraint: ">10.0.0", matches: []string{"10.0.1"}, misses: []string{"10"}},
+		{constraint: ">5.x", matches: []string{"6.0.0"}, misses: []string{"5.9.9"}},
+		{constraint: "<5", matches: []string{"4.9.9"}, misses: []string{"5", "5.0.1"}},
+		{constraint: "<=5.2", matches: []string{"5.2.9", "5.1"}, misses: []string{"5.3.0"}},
+		{constraint: "4.2.0 - 5.x", matches: []string{"4.2.0", "4.10", "5.9.1"}, misses: []string{"4.1.9", "6.0.0"}},
+		{constraint: ">=4.2.0 <6", matches: []string{"5.0.0"}, misses: []string{"6.0.0", "4.1"}},
+		{constraint: ">=4.2.0, <6", matches: []string{"5.0.0"}, misses: []string{"6.0.0"}},
+		{constraint: "5.*", matches: []string{"5", "5.4.3"}, misses: []string{"4.9", "6"}},
+		{constraint: "4.2.0", matches: []string{"4.2", "v4.2.0"}, misses: []string{"4.2.1"}},
+		{constraint: "*", matches: []string{"0.0.1", "99"}},
+	}
+	for _, tc := range tests {
+		c, err := ParseConstraint(tc.constraint)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.constraint, err)
+			continue
+		}
+		for _, raw := range tc.matches {
+			if !c.Check(mustParse(t, raw)) {
+				t.Errorf("Expected %s to satisfy %q", raw, tc.constraint)
+			}
+		}
+		for _, raw := range tc.misses {
+			if c.Check(mustParse(t, raw)) {
+				t.Errorf("Expected %s not to satisfy %q", raw, tc.constraint)
+			}
+		}
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, input := range []string{"", ">=", ">x", ">=abc", "1.x.2", "4.2.0 - "} {
+		if _, err := ParseConstraint(input); err == nil {
+			t.Errorf("Expected %q to be rejected", input)
+		}
+	}
+}
+
+func mustParse(t *testing.T, s string) Version {
+	t.Helper()
+	v, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", s, err)
+	}
+	return v
+}